@@ -0,0 +1,153 @@
+package weather
+
+import (
+	"sync"
+	"time"
+)
+
+// BreakerState is the state of a circuit breaker.
+type BreakerState string
+
+// List of breaker states.
+const (
+	// BreakerClosed lets every call through.
+	BreakerClosed BreakerState = "closed"
+
+	// BreakerOpen refuses calls until its cooldown has passed.
+	BreakerOpen BreakerState = "open"
+
+	// BreakerHalfOpen lets a single trial call through, its outcome closes or reopens the breaker.
+	BreakerHalfOpen BreakerState = "half-open"
+)
+
+// Breaker is a circuit breaker that opens after a number of consecutive failures,
+// so that a failing dependency is given time to recover instead of being called on every request.
+type Breaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu          sync.Mutex
+	state       BreakerState
+	failures    int
+	openedAt    time.Time
+	trial       bool
+	lastSuccess time.Time
+	lastFailure time.Time
+}
+
+// NewBreaker returns a closed breaker that opens after threshold consecutive failures and stays open for the cooldown.
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+
+	return &Breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+		state:     BreakerClosed,
+	}
+}
+
+// Allow reports whether a call may be made. Once the cooldown has passed an open breaker allows one trial call.
+func (b *Breaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = BreakerHalfOpen
+		b.trial = true
+		return true
+	case BreakerHalfOpen:
+		if b.trial {
+			return false
+		}
+		b.trial = true
+		return true
+	}
+
+	return true
+}
+
+// Success records a successful call and closes the breaker.
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = BreakerClosed
+	b.failures = 0
+	b.trial = false
+	b.lastSuccess = b.now()
+}
+
+// Failure records a failed call. The breaker opens once the threshold is reached or when a trial call fails.
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.trial = false
+	b.lastFailure = b.now()
+	if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+		b.state = BreakerOpen
+		b.openedAt = b.now()
+	}
+}
+
+// Release ends a call whose outcome says nothing about the dependency's health, such as a call the caller gave up on.
+// The failures are kept, a trial call that is released lets the next call be a trial.
+func (b *Breaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+}
+
+// State returns the current state of the breaker.
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.current()
+}
+
+// current returns the current state of the breaker, the caller must hold the lock.
+func (b *Breaker) current() BreakerState {
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.cooldown {
+		return BreakerHalfOpen
+	}
+
+	return b.state
+}
+
+// BreakerStatus is a snapshot of a breaker: its state, its consecutive failures and when the last calls succeeded and failed.
+// LastSuccess and LastFailure are nil until a call succeeds or fails.
+type BreakerStatus struct {
+	State       BreakerState `json:"state"`
+	Failures    int          `json:"consecutive_failures"`
+	LastSuccess *time.Time   `json:"last_success,omitempty"`
+	LastFailure *time.Time   `json:"last_failure,omitempty"`
+}
+
+// Status returns a snapshot of the breaker.
+func (b *Breaker) Status() BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := BreakerStatus{State: b.current(), Failures: b.failures}
+	if !b.lastSuccess.IsZero() {
+		t := b.lastSuccess
+		status.LastSuccess = &t
+	}
+	if !b.lastFailure.IsZero() {
+		t := b.lastFailure
+		status.LastFailure = &t
+	}
+
+	return status
+}
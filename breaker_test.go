@@ -0,0 +1,56 @@
+package weather
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreaker(t *testing.T) {
+	now := time.Date(2020, 12, 17, 17, 0, 0, 0, time.UTC)
+	start := now
+	b := NewBreaker(2, time.Minute)
+	b.now = func() time.Time { return now }
+	assert.Equal(t, BreakerStatus{State: BreakerClosed}, b.Status())
+
+	// A success resets the consecutive failures
+	b.Failure()
+	b.Success()
+	b.Failure()
+	assert.True(t, b.Allow())
+	assert.Equal(t, BreakerClosed, b.State())
+
+	b.Failure()
+	assert.False(t, b.Allow())
+	assert.Equal(t, BreakerOpen, b.State())
+
+	// After the cooldown a single trial is allowed, a failed trial reopens the breaker
+	now = now.Add(time.Minute)
+	assert.Equal(t, BreakerHalfOpen, b.State())
+	assert.True(t, b.Allow())
+	assert.False(t, b.Allow())
+	b.Failure()
+	assert.False(t, b.Allow())
+
+	assert.Equal(t, BreakerStatus{State: BreakerOpen, Failures: 3, LastSuccess: &start, LastFailure: &now}, b.Status())
+
+	// A released trial lets the next call be a trial
+	now = now.Add(time.Minute)
+	assert.True(t, b.Allow())
+	b.Release()
+	assert.Equal(t, BreakerHalfOpen, b.State())
+	assert.True(t, b.Allow())
+	b.Failure()
+	assert.False(t, b.Allow())
+
+	// A successful trial closes it
+	failed := now
+	now = now.Add(time.Minute)
+	assert.True(t, b.Allow())
+	b.Success()
+	assert.Equal(t, BreakerClosed, b.State())
+	assert.True(t, b.Allow())
+	assert.True(t, b.Allow())
+	assert.Equal(t, BreakerStatus{State: BreakerClosed, LastSuccess: &now, LastFailure: &failed}, b.Status())
+}
@@ -0,0 +1,245 @@
+package weather
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrNoProvider is returned when every provider in a chain is unavailable.
+var ErrNoProvider = errors.New("No weather provider is available, please try again later")
+
+// Breaker settings of the providers in a chain.
+const (
+	chainBreakerThreshold = 5
+	chainBreakerCooldown  = 30 * time.Second
+)
+
+// ProviderChain is a provider that calls its providers in order until one succeeds,
+// so that an outage, timeout or exhausted quota of one provider is transparent to clients.
+// Each provider has a circuit breaker, providers with an open breaker are skipped.
+type ProviderChain struct {
+	links   []chainLink
+	timeout time.Duration
+}
+
+type chainLink struct {
+	provider Provider
+	breaker  *Breaker
+}
+
+// NewProviderChain returns a chain of the providers in order of preference.
+// Every call to a provider is given the timeout, a timeout of zero means calls are only bound by the caller's context.
+func NewProviderChain(providers []Provider, timeout time.Duration) *ProviderChain {
+	c := &ProviderChain{timeout: timeout}
+	for _, p := range providers {
+		c.links = append(c.links, chainLink{provider: p, breaker: NewBreaker(chainBreakerThreshold, chainBreakerCooldown)})
+	}
+
+	return c
+}
+
+// Name returns the names of the providers in the chain.
+func (c *ProviderChain) Name() string {
+	name := "chain"
+	for i, l := range c.links {
+		sep := ","
+		if i == 0 {
+			sep = ":"
+		}
+		name += sep + l.provider.Name()
+	}
+
+	return name
+}
+
+// ProviderHealth is the health of a provider of a chain, as seen by its circuit breaker.
+// A provider is available unless its breaker is open.
+type ProviderHealth struct {
+	Provider  string `json:"provider"`
+	Available bool   `json:"available"`
+	BreakerStatus
+}
+
+// Health returns the health of the providers in the chain, in order.
+func (c *ProviderChain) Health() []ProviderHealth {
+	health := make([]ProviderHealth, len(c.links))
+	for i, l := range c.links {
+		status := l.breaker.Status()
+		health[i] = ProviderHealth{Provider: l.provider.Name(), Available: status.State != BreakerOpen, BreakerStatus: status}
+	}
+
+	return health
+}
+
+// Current returns the current conditions from the first provider that succeeds.
+func (c *ProviderChain) Current(ctx context.Context, loc Location, opts Options) (*Observation, error) {
+	obs, _, err := c.CurrentFrom(ctx, loc, opts)
+	return obs, err
+}
+
+// CurrentFrom returns the current conditions from the first provider that succeeds, along with that provider's name.
+func (c *ProviderChain) CurrentFrom(ctx context.Context, loc Location, opts Options) (*Observation, string, error) {
+	var obs *Observation
+	name, err := c.try(ctx, func(ctx context.Context, p Provider) (err error) {
+		obs, err = p.Current(ctx, loc, opts)
+		if err != nil {
+			return err
+		}
+		return obs.Validate()
+	})
+
+	return obs, name, err
+}
+
+// Forecast returns the forecast from the first provider that succeeds.
+func (c *ProviderChain) Forecast(ctx context.Context, coord Coord, opts Options) (*Forecast, error) {
+	f, _, err := c.ForecastFrom(ctx, coord, opts)
+	return f, err
+}
+
+// ForecastFrom returns the forecast from the first provider that succeeds, along with that provider's name.
+func (c *ProviderChain) ForecastFrom(ctx context.Context, coord Coord, opts Options) (*Forecast, string, error) {
+	var f *Forecast
+	name, err := c.try(ctx, func(ctx context.Context, p Provider) (err error) {
+		f, err = p.Forecast(ctx, coord, opts)
+		if err != nil {
+			return err
+		}
+		return f.Validate()
+	})
+
+	return f, name, err
+}
+
+// Geocode returns the coordinates from the first provider that succeeds.
+func (c *ProviderChain) Geocode(ctx context.Context, loc Location) (Coord, error) {
+	var coord Coord
+	_, err := c.try(ctx, func(ctx context.Context, p Provider) (err error) {
+		coord, err = p.Geocode(ctx, loc)
+		if err != nil {
+			return err
+		}
+		return coord.Validate()
+	})
+
+	return coord, err
+}
+
+// Ensemble returns the daily forecasts of every provider whose breaker allows it, in the order of the chain.
+// The providers are called in parallel, providers that fail are left out.
+// An error is returned only when no provider succeeds.
+func (c *ProviderChain) Ensemble(ctx context.Context, coord Coord, opts Options) ([]ProviderForecast, error) {
+	var (
+		wg        sync.WaitGroup
+		forecasts = make([]ProviderForecast, len(c.links))
+		errs      = make([]error, len(c.links))
+	)
+
+	for i, l := range c.links {
+		if !l.breaker.Allow() {
+			errs[i] = ErrNoProvider
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, l chainLink) {
+			defer wg.Done()
+
+			var f *Forecast
+			errs[i] = c.call(ctx, l.provider, func(ctx context.Context, p Provider) (err error) {
+				f, err = p.Forecast(ctx, coord, opts)
+				if err != nil {
+					return err
+				}
+				return f.Validate()
+			})
+			if f != nil {
+				forecasts[i] = ProviderForecast{Provider: l.provider.Name(), Daily: f.Daily}
+			}
+			c.record(ctx, l, errs[i])
+		}(i, l)
+	}
+	wg.Wait()
+
+	var (
+		result []ProviderForecast
+		err    error = ErrNoProvider
+	)
+	for i := range c.links {
+		if errs[i] == nil {
+			result = append(result, forecasts[i])
+		} else if errs[i] != ErrNoProvider {
+			err = fmt.Errorf("%s: %w", c.links[i].provider.Name(), errs[i])
+		}
+	}
+
+	if len(result) == 0 {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// try calls fn with each provider whose breaker allows it until a call succeeds, and returns that provider's name.
+// When every call fails the last error is returned, prefixed with the provider's name.
+func (c *ProviderChain) try(ctx context.Context, fn func(context.Context, Provider) error) (string, error) {
+	err := ErrNoProvider
+	for _, l := range c.links {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+
+		if !l.breaker.Allow() {
+			continue
+		}
+
+		callErr := c.call(ctx, l.provider, fn)
+		c.record(ctx, l, callErr)
+		if callErr == nil {
+			return l.provider.Name(), nil
+		}
+
+		err = fmt.Errorf("%s: %w", l.provider.Name(), callErr)
+	}
+
+	return "", err
+}
+
+// call calls fn with the provider, bound by the chain's timeout.
+func (c *ProviderChain) call(ctx context.Context, p Provider, fn func(context.Context, Provider) error) error {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	return fn(ctx, p)
+}
+
+// record reports the outcome of a call to the provider's breaker, every call allowed by the breaker must be recorded.
+// Invalid payloads are logged, so that anomalies of a provider are visible even when another provider serves the request.
+func (c *ProviderChain) record(ctx context.Context, l chainLink, err error) {
+	if err == nil {
+		l.breaker.Success()
+		return
+	}
+
+	if errors.Is(err, ErrInvalidPayload) {
+		Logger(ctx).WithField("provider", l.provider.Name()).Warn(err)
+	}
+
+	// Unknown locations, calls shed by the quota and failures caused by the caller giving up say nothing about the
+	// provider's health
+	if ctx.Err() != nil || errors.Is(err, ErrLocationNotFound) || errors.Is(err, ErrQuotaExceeded) {
+		l.breaker.Release()
+		return
+	}
+
+	l.breaker.Failure()
+}
@@ -0,0 +1,208 @@
+package weather
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeProvider is a provider answering with fixed data or a fixed error.
+// When shed is set, non-essential calls are refused as if the quota was near its limit.
+type fakeProvider struct {
+	name  string
+	err   error
+	delay time.Duration
+	shed  bool
+	calls int
+}
+
+func (f *fakeProvider) Name() string {
+	return f.name
+}
+
+func (f *fakeProvider) Current(ctx context.Context, loc Location, opts Options) (*Observation, error) {
+	if err := f.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	return &Observation{Name: f.name}, nil
+}
+
+func (f *fakeProvider) Forecast(ctx context.Context, coord Coord, opts Options) (*Forecast, error) {
+	if err := f.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	return &Forecast{Daily: []DailyForecast{{TempMax: float64(len(f.name))}}}, nil
+}
+
+func (f *fakeProvider) Geocode(ctx context.Context, loc Location) (Coord, error) {
+	if err := f.wait(ctx); err != nil {
+		return Coord{}, err
+	}
+
+	return Coord{Lat: float64(len(f.name))}, nil
+}
+
+func (f *fakeProvider) wait(ctx context.Context) error {
+	f.calls++
+	if f.shed && PriorityFrom(ctx) == NonEssential {
+		return ErrQuotaExceeded
+	}
+	if f.delay > 0 {
+		select {
+		case <-time.After(f.delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return f.err
+}
+
+func TestProviderChainFailover(t *testing.T) {
+	primary := &fakeProvider{name: "primary", err: ErrQuotaExceeded}
+	secondary := &fakeProvider{name: "secondary"}
+	chain := NewProviderChain([]Provider{primary, secondary}, time.Second)
+	ctx := context.Background()
+
+	owr, name, err := chain.CurrentFrom(ctx, Location{}, Options{})
+	assert.Nil(t, err)
+	assert.Equal(t, "secondary", name)
+	assert.Equal(t, "secondary", owr.Name)
+
+	f, name, err := chain.ForecastFrom(ctx, Coord{}, Options{})
+	assert.Nil(t, err)
+	assert.Equal(t, "secondary", name)
+	assert.Equal(t, []DailyForecast{{TempMax: 9}}, f.Daily)
+
+	// The primary's breaker opens after repeated failures, so it is no longer called
+	primary.err, primary.calls = errors.New("unavailable"), 0
+	for i := 0; i < chainBreakerThreshold; i++ {
+		_, err := chain.Geocode(ctx, Location{})
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, chainBreakerThreshold, primary.calls)
+	assert.Equal(t, BreakerOpen, chain.links[0].breaker.State())
+
+	health := chain.Health()
+	assert.Equal(t, "primary", health[0].Provider)
+	assert.False(t, health[0].Available)
+	assert.Equal(t, chainBreakerThreshold, health[0].Failures)
+	assert.Nil(t, health[0].LastSuccess)
+	assert.True(t, health[1].Available)
+	assert.NotNil(t, health[1].LastSuccess)
+
+	// When every provider fails, the last error is returned
+	secondary.err = errors.New("unavailable")
+	_, err = chain.Current(ctx, Location{}, Options{})
+	assert.Equal(t, "secondary: unavailable", err.Error())
+	assert.Equal(t, "chain:primary,secondary", chain.Name())
+}
+
+func TestProviderChainTimeout(t *testing.T) {
+	slow := &fakeProvider{name: "slow", delay: time.Minute}
+	fast := &fakeProvider{name: "fast"}
+	chain := NewProviderChain([]Provider{slow, fast}, 10*time.Millisecond)
+
+	_, name, err := chain.CurrentFrom(context.Background(), Location{}, Options{})
+	assert.Nil(t, err)
+	assert.Equal(t, "fast", name)
+
+	// A cancelled caller does not fail over
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = chain.Current(ctx, Location{}, Options{})
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 2, fast.calls+slow.calls)
+}
+
+func TestProviderChainLocationNotFound(t *testing.T) {
+	primary := &fakeProvider{name: "primary", err: ErrLocationNotFound}
+	chain := NewProviderChain([]Provider{primary}, 0)
+
+	for i := 0; i < chainBreakerThreshold; i++ {
+		_, err := chain.Geocode(context.Background(), Location{})
+		assert.True(t, errors.Is(err, ErrLocationNotFound))
+	}
+	assert.Equal(t, BreakerClosed, chain.links[0].breaker.State())
+}
+
+func TestProviderChainQuotaShed(t *testing.T) {
+	primary := &fakeProvider{name: "primary", shed: true}
+	chain := NewProviderChain([]Provider{primary}, 0)
+
+	// Calls shed by the quota do not open the breaker, essential calls are still served
+	ctx := WithPriority(context.Background(), NonEssential)
+	for i := 0; i < 2*chainBreakerThreshold; i++ {
+		_, err := chain.Current(ctx, Location{}, Options{})
+		assert.True(t, errors.Is(err, ErrQuotaExceeded))
+	}
+	assert.Equal(t, BreakerClosed, chain.links[0].breaker.State())
+	assert.True(t, chain.Health()[0].Available)
+
+	obs, err := chain.Current(context.Background(), Location{}, Options{})
+	assert.Nil(t, err)
+	assert.Equal(t, "primary", obs.Name)
+}
+
+func TestProviderChainTrialReleased(t *testing.T) {
+	primary := &fakeProvider{name: "primary", err: errors.New("unavailable")}
+	chain := NewProviderChain([]Provider{primary}, 0)
+	breaker := chain.links[0].breaker
+	now := time.Now()
+	breaker.now = func() time.Time { return now }
+
+	open := func() {
+		primary.err, primary.delay = errors.New("unavailable"), 0
+		for i := 0; i < chainBreakerThreshold; i++ {
+			chain.Geocode(context.Background(), Location{})
+		}
+		assert.Equal(t, BreakerOpen, breaker.State())
+		now = now.Add(chainBreakerCooldown)
+		assert.Equal(t, BreakerHalfOpen, breaker.State())
+	}
+
+	// A trial ending with an unknown location does not keep the provider unavailable
+	open()
+	primary.err = ErrLocationNotFound
+	_, err := chain.Geocode(context.Background(), Location{})
+	assert.True(t, errors.Is(err, ErrLocationNotFound))
+	primary.err = nil
+	_, err = chain.Geocode(context.Background(), Location{})
+	assert.Nil(t, err)
+	assert.Equal(t, BreakerClosed, breaker.State())
+
+	// Nor does a trial the caller gave up on
+	open()
+	primary.err, primary.delay = nil, time.Minute
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = chain.Geocode(ctx, Location{})
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.True(t, chain.Health()[0].Available)
+	primary.delay = 0
+	_, err = chain.Geocode(context.Background(), Location{})
+	assert.Nil(t, err)
+	assert.Equal(t, BreakerClosed, breaker.State())
+}
+
+func TestProviderChainEnsemble(t *testing.T) {
+	primary := &fakeProvider{name: "primary"}
+	failing := &fakeProvider{name: "failing", err: errors.New("unavailable")}
+	secondary := &fakeProvider{name: "secondary"}
+	chain := NewProviderChain([]Provider{primary, failing, secondary}, time.Second)
+
+	forecasts, err := chain.Ensemble(context.Background(), Coord{}, Options{})
+	assert.Nil(t, err)
+	assert.Equal(t, []ProviderForecast{{Provider: "primary", Daily: []DailyForecast{{TempMax: 7}}}, {Provider: "secondary", Daily: []DailyForecast{{TempMax: 9}}}}, forecasts)
+
+	// When every provider fails, an error is returned
+	primary.err = errors.New("unavailable")
+	secondary.err = errors.New("unavailable")
+	_, err = chain.Ensemble(context.Background(), Coord{}, Options{})
+	assert.Equal(t, "secondary: unavailable", err.Error())
+}
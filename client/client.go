@@ -0,0 +1,220 @@
+// Package client is a Go client for the weather API server.
+//
+//	c := client.New("http://localhost:8080")
+//	hr, err := c.Weather(ctx, client.City("Bogota", "CO"), client.Day(1), client.Units(weather.Imperial))
+//
+// Error responses are returned as *Error values, which match the errors of this package and of the weather package
+// with errors.Is, e.g. errors.Is(err, weather.ErrLocationNotFound).
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mpfrancis/weather"
+)
+
+// maxForecastDays is the number of days forecast by the server, today included.
+const maxForecastDays = 7
+
+// ErrInvalidDays is returned when a forecast for fewer than one or more than seven days is requested.
+var ErrInvalidDays = errors.New("Forecast days must be between 1 and 7")
+
+// Client calls the /weather endpoint of a weather API server.
+// Requests failing with a network error or a 5xx status are retried.
+type Client struct {
+	baseURL string
+	http    weather.Client
+	retries int
+	backoff time.Duration
+}
+
+// Option configures a client.
+type Option func(*Client)
+
+// WithHTTPClient sets the client used to send requests, http.DefaultClient by default.
+func WithHTTPClient(c weather.Client) Option {
+	return func(client *Client) {
+		client.http = c
+	}
+}
+
+// WithRetries sets how many times a failed request is retried, waiting for the backoff before the first retry.
+// The wait doubles for every following retry.
+func WithRetries(retries int, backoff time.Duration) Option {
+	return func(client *Client) {
+		client.retries = retries
+		client.backoff = backoff
+	}
+}
+
+// New returns a client for the server at baseURL, e.g. "http://localhost:8080".
+// Failed requests are retried twice by default, after 200ms and 400ms.
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		http:    http.DefaultClient,
+		retries: 2,
+		backoff: 200 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// City returns the location of a city, the country is a two letter ISO 3166 country code.
+func City(city, country string) weather.Location {
+	return weather.Location{City: city, Country: country}
+}
+
+// Zip returns the location of a postal code, the country is a two letter ISO 3166 country code.
+func Zip(zip, country string) weather.Location {
+	return weather.Location{Zip: zip, Country: country}
+}
+
+// Coordinates returns the location at the latitude and longitude.
+func Coordinates(lat, lon float64) weather.Location {
+	return weather.Location{Coord: &weather.Coord{Lat: lat, Lon: lon}}
+}
+
+// RequestOption sets an optional parameter of a weather request.
+type RequestOption func(url.Values)
+
+// Day requests the forecast for the day, from 0 for today to 6.
+func Day(day int) RequestOption {
+	return func(query url.Values) {
+		query.Set("forecast", strconv.Itoa(day))
+	}
+}
+
+// Units requests temperatures and wind speeds in the units, the server's configured units are used by default.
+func Units(units weather.Unit) RequestOption {
+	return func(query url.Values) {
+		query.Set("units", string(units))
+	}
+}
+
+// Lang requests descriptions in the language, e.g. "es" or "pt_br", english is used by default.
+func Lang(lang string) RequestOption {
+	return func(query url.Values) {
+		query.Set("lang", lang)
+	}
+}
+
+// Ensemble requests the forecast combined from every provider, it must be used along with Day.
+func Ensemble() RequestOption {
+	return func(query url.Values) {
+		query.Set("ensemble", "true")
+	}
+}
+
+// Weather returns the current conditions at the location, along with a forecast when requested with Day.
+func (c *Client) Weather(ctx context.Context, loc weather.Location, opts ...RequestOption) (*weather.HumanReadableResponse, error) {
+	query := url.Values{}
+	switch {
+	case loc.Coord != nil:
+		query.Set("lat", strconv.FormatFloat(loc.Coord.Lat, 'f', -1, 64))
+		query.Set("lon", strconv.FormatFloat(loc.Coord.Lon, 'f', -1, 64))
+	case loc.Zip != "":
+		query.Set("zip", loc.Zip)
+		query.Set("country", loc.Country)
+	default:
+		query.Set("city", loc.City)
+		query.Set("country", loc.Country)
+	}
+
+	for _, opt := range opts {
+		opt(query)
+	}
+
+	return c.get(ctx, query)
+}
+
+// Forecast returns the forecast at the location for the given number of days, today included.
+func (c *Client) Forecast(ctx context.Context, loc weather.Location, days int, opts ...RequestOption) ([]weather.DailyForecast, error) {
+	if days < 1 || maxForecastDays < days {
+		return nil, ErrInvalidDays
+	}
+
+	forecast := make([]weather.DailyForecast, days)
+	for day := range forecast {
+		hr, err := c.Weather(ctx, loc, append(opts, Day(day))...)
+		if err != nil {
+			return nil, err
+		}
+
+		if hr.Forecast == nil {
+			return nil, fmt.Errorf("Weather response has no forecast for day %d", day)
+		}
+		forecast[day] = *hr.Forecast
+	}
+
+	return forecast, nil
+}
+
+// get calls the /weather endpoint, retrying failed requests until the retries are used up or the context is done.
+func (c *Client) get(ctx context.Context, query url.Values) (*weather.HumanReadableResponse, error) {
+	var (
+		hr    *weather.HumanReadableResponse
+		retry bool
+		err   error
+	)
+
+	for attempt := 0; attempt <= c.retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(c.backoff << (attempt - 1)):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		if hr, retry, err = c.do(ctx, query); !retry {
+			break
+		}
+	}
+
+	return hr, err
+}
+
+// do sends a single request, it reports whether the request may succeed when retried.
+func (c *Client) do(ctx context.Context, query url.Values) (*weather.HumanReadableResponse, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/weather?"+query.Encode(), nil)
+	if err != nil {
+		return nil, false, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, false, ctx.Err()
+		}
+		return nil, true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+		err := &Error{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(body))}
+		return nil, resp.StatusCode >= http.StatusInternalServerError, err
+	}
+
+	var hr weather.HumanReadableResponse
+	if err := json.NewDecoder(resp.Body).Decode(&hr); err != nil {
+		return nil, false, err
+	}
+
+	return &hr, false, nil
+}
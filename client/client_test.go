@@ -0,0 +1,153 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mpfrancis/weather"
+	internalhttp "github.com/mpfrancis/weather/internal/http"
+	"github.com/mpfrancis/weather/internal/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+type queryCase struct {
+	location weather.Location
+	opts     []RequestOption
+	expected string
+}
+
+func TestClientWeather(t *testing.T) {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/weather", r.URL.Path)
+		query = r.URL.RawQuery
+		fmt.Fprint(w, `{"location_name":"Bogotá, CO","temperature":"20 °C","forecast":{"temp_max":19.68}}`)
+	}))
+	defer server.Close()
+
+	c := New(server.URL + "/")
+	cases := []queryCase{
+		{City("Bogota", "CO"), nil, "city=Bogota&country=CO"},
+		{Zip("10001", "US"), []RequestOption{Units(weather.Imperial)}, "country=US&units=imperial&zip=10001"},
+		{Coordinates(4.61, -74.08), []RequestOption{Day(2), Lang("es"), Ensemble()}, "ensemble=true&forecast=2&lang=es&lat=4.61&lon=-74.08"},
+	}
+
+	for i := range cases {
+		hr, err := c.Weather(context.Background(), cases[i].location, cases[i].opts...)
+		assert.Nil(t, err)
+		assert.Equal(t, cases[i].expected, query)
+		assert.Equal(t, "Bogotá, CO", hr.LocationName)
+		assert.Equal(t, 19.68, hr.Forecast.TempMax)
+	}
+}
+
+type errorCase struct {
+	status  int
+	body    string
+	matches []error
+}
+
+func TestClientErrors(t *testing.T) {
+	cases := []errorCase{
+		{422, "Query parameter 'city' is required\n", []error{ErrInvalidRequest}},
+		{404, "openweather responded with status 404: city not found\n", []error{weather.ErrLocationNotFound}},
+		{502, "Invalid upstream payload, name is missing ()\n", []error{ErrUpstream}},
+		{503, weather.ErrQuotaExceeded.Error() + "\n", []error{ErrUnavailable, weather.ErrQuotaExceeded}},
+		{503, weather.ErrNoProvider.Error() + "\n", []error{ErrUnavailable, weather.ErrNoProvider}},
+	}
+
+	for i := range cases {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			http.Error(w, strings.TrimSuffix(cases[i].body, "\n"), cases[i].status)
+		}))
+
+		c := New(server.URL, WithRetries(1, time.Millisecond))
+		_, err := c.Weather(context.Background(), City("Bogota", "CO"))
+		assert.Equal(t, &Error{StatusCode: cases[i].status, Message: strings.TrimSuffix(cases[i].body, "\n")}, err)
+		for _, target := range cases[i].matches {
+			assert.True(t, errors.Is(err, target), "%d %s", cases[i].status, target)
+		}
+		assert.False(t, errors.Is(err, weather.ErrNoAPIKey))
+
+		// Only server errors are retried
+		assert.Equal(t, map[bool]int32{false: 1, true: 2}[cases[i].status >= 500], atomic.LoadInt32(&calls), cases[i].status)
+		server.Close()
+	}
+
+	_, err := New("http://localhost").Forecast(context.Background(), City("Bogota", "CO"), 8)
+	assert.Equal(t, ErrInvalidDays, err)
+}
+
+func TestClientRetries(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			http.Error(w, "No weather provider is available, please try again later", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{"location_name":"Bogotá, CO"}`)
+	}))
+	defer server.Close()
+
+	c := New(server.URL, WithRetries(2, time.Millisecond))
+	hr, err := c.Weather(context.Background(), City("Bogota", "CO"))
+	assert.Nil(t, err)
+	assert.Equal(t, "Bogotá, CO", hr.LocationName)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+
+	// Retries stop once the context is done
+	atomic.StoreInt32(&calls, 0)
+	c = New(server.URL, WithRetries(2, time.Minute))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = c.Weather(ctx, City("Bogota", "CO"))
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.True(t, time.Since(start) < 5*time.Second)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+// TestClientServer checks that the client's requests are understood by the server.
+func TestClientServer(t *testing.T) {
+	cfg := weather.Config{Units: weather.Metric}
+	var upstream mock.Client
+	upstream.GetFn = func(url string) (*http.Response, error) {
+		body := `{"coord": {"lon": -74.08, "lat": 4.61}, "name": "Bogotá", "sys": {"country": "CO"}, "main": {"temp": 20}}`
+		if strings.Contains(url, "/onecall?") {
+			body = `{"daily": [{"dt": 1608825600, "temp": {"min": 9, "max": 19}}, {"dt": 1608912000, "temp": {"min": 10, "max": 18}}]}`
+		}
+
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(body))}, nil
+	}
+
+	s := internalhttp.NewServer(&cfg, &upstream)
+	defer s.Shutdown(context.Background())
+	server := httptest.NewServer(s.Handler)
+	defer server.Close()
+
+	c := New(server.URL)
+	ctx := context.Background()
+	for _, loc := range []weather.Location{City("Bogota", "CO"), Zip("110111", "CO"), Coordinates(4.61, -74.08)} {
+		hr, err := c.Weather(ctx, loc)
+		assert.Nil(t, err)
+		assert.Equal(t, "20 °C", hr.Temperature)
+	}
+
+	forecast, err := c.Forecast(ctx, City("Bogota", "CO"), 2)
+	assert.Nil(t, err)
+	assert.Equal(t, []float64{19, 18}, []float64{forecast[0].TempMax, forecast[1].TempMax})
+
+	_, err = c.Weather(ctx, City("Bogota", "CO"), Day(9))
+	assert.True(t, errors.Is(err, ErrInvalidRequest))
+}
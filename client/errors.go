@@ -0,0 +1,49 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/mpfrancis/weather"
+)
+
+// List of errors matched by error responses, according to their status.
+var (
+	ErrInvalidRequest = errors.New("Invalid weather request")
+	ErrUpstream       = errors.New("Weather upstream failed")
+	ErrUnavailable    = errors.New("Weather service unavailable")
+)
+
+// Error is an error response of the weather API server.
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+// Error returns the status and message of the response.
+func (e *Error) Error() string {
+	return fmt.Sprintf("Weather API responded with status %d: %s", e.StatusCode, e.Message)
+}
+
+// Is matches the error with the errors of its status:
+// ErrInvalidRequest for 422, weather.ErrLocationNotFound for 404, ErrUpstream for 502 and ErrUnavailable for 503.
+// Unavailable services also match the reason reported by the server,
+// i.e. weather.ErrQuotaExceeded, weather.ErrNoAPIKey or weather.ErrNoProvider.
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrInvalidRequest:
+		return e.StatusCode == http.StatusUnprocessableEntity
+	case weather.ErrLocationNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrUpstream:
+		return e.StatusCode == http.StatusBadGateway
+	case ErrUnavailable:
+		return e.StatusCode == http.StatusServiceUnavailable
+	case weather.ErrQuotaExceeded, weather.ErrNoAPIKey, weather.ErrNoProvider:
+		return e.StatusCode == http.StatusServiceUnavailable && strings.Contains(e.Message, target.Error())
+	}
+
+	return false
+}
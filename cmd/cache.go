@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"text/tabwriter"
+
+	weatherhttp "github.com/mpfrancis/weather/internal/http"
+	weatheros "github.com/mpfrancis/weather/internal/os"
+)
+
+// cacheCommand reports or flushes the caches of a running server through its /admin/cache endpoint, with the admin token.
+func cacheCommand(ctx context.Context, args []string, out io.Writer) error {
+	if len(args) == 0 || (args[0] != "stats" && args[0] != "flush") {
+		return fmt.Errorf("%w, use: cache stats, cache flush", errUsage)
+	}
+
+	fs := flag.NewFlagSet("cache "+args[0], flag.ContinueOnError)
+	fs.SetOutput(out)
+	server := fs.String("server", localServer(weatheros.ServerAddress()), "URL of the running server")
+	// The token from the environment is not the flag's default, so that it is not printed along with the usage
+	token := fs.String("token", "", "admin token of the running server, SERVER_ADMIN_TOKEN by default")
+	if err := parseFlags(fs, args[1:]); err != nil {
+		return err
+	}
+
+	if *token == "" {
+		*token = weatheros.AdminToken()
+	}
+
+	if fs.NArg() > 0 {
+		return fmt.Errorf("%w, cache %s takes no arguments", errUsage, args[0])
+	}
+
+	method := http.MethodGet
+	if args[0] == "flush" {
+		method = http.MethodDelete
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(*server, "/")+"/admin/cache", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+*token)
+
+	resp, err := weatherhttp.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("%s responded with status %d: %s", *server, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	if method == http.MethodDelete {
+		fmt.Fprintln(out, "Cache flushed")
+		return nil
+	}
+
+	var stats weatherhttp.CacheStats
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "Responses\t%d\n", stats.Responses)
+	fmt.Fprintf(tw, "Hits\t%d\n", stats.Hits)
+	fmt.Fprintf(tw, "Misses\t%d\n", stats.Misses)
+	fmt.Fprintf(tw, "Evictions\t%d\n", stats.Evictions)
+	fmt.Fprintf(tw, "Observations\t%d\n", stats.Observations)
+	fmt.Fprintf(tw, "Forecasts\t%d\n", stats.Forecasts)
+	fmt.Fprintf(tw, "Coordinates\t%d\n", stats.Coordinates)
+	return tw.Flush()
+}
+
+// localServer returns the URL of the server listening on the address on this host.
+func localServer(addr string) string {
+	if strings.HasPrefix(addr, ":") {
+		addr = "localhost" + addr
+	}
+
+	return "http://" + addr
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/mpfrancis/weather"
+	weatheros "github.com/mpfrancis/weather/internal/os"
+)
+
+// errCheckFailed is returned by check-config when a setting is invalid or an upstream check fails.
+var errCheckFailed = errors.New("Configuration check failed")
+
+// probe is the location looked up to verify the providers.
+var probe = weather.Location{City: "london", Country: "GB"}
+
+// unlimited is the quota of the upstream checks, each check makes a single call.
+type unlimited struct{}
+
+func (unlimited) Reserve(p weather.Priority) error {
+	return nil
+}
+
+// checkConfig prints every problem of the configuration, or the effective settings with the API keys masked
+// followed by a check of every provider against its upstream.
+// Every open weather API key is checked on its own, each check is an upstream call.
+func checkConfig(ctx context.Context, args []string, client weather.Client, out io.Writer) error {
+	fs, opts := configFlags("check-config", out)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if fs.NArg() > 0 {
+		return fmt.Errorf("%w, check-config takes no arguments", errUsage)
+	}
+
+	cfg, err := weatheros.Load(*opts)
+	if err != nil {
+		var errs weatheros.Errors
+		if !errors.As(err, &errs) {
+			errs = weatheros.Errors{err}
+		}
+
+		for _, err := range errs {
+			fmt.Fprintln(out, "error:", err)
+		}
+		return errCheckFailed
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	for _, s := range weatheros.Settings(cfg) {
+		fmt.Fprintf(tw, "%s\t%s\n", s.Name, s.Value)
+	}
+	fmt.Fprintln(tw)
+
+	ok := true
+	for _, name := range cfg.Providers {
+		switch name {
+		case weather.OpenWeatherProvider:
+			for _, key := range cfg.Keys() {
+				provider := weather.NewOpenWeather(cfg, client, weather.NewKeyRing([]weather.APIKey{key}), unlimited{})
+				ok = report(tw, name+" key "+weatheros.MaskKey(key.Key), checkProvider(ctx, cfg, provider)) && ok
+			}
+		case weather.OpenMeteoProvider:
+			ok = report(tw, name, checkProvider(ctx, cfg, weather.NewOpenMeteo(cfg, client))) && ok
+		}
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if !ok {
+		return errCheckFailed
+	}
+
+	return nil
+}
+
+// checkProvider looks the probe location up with the provider, within the provider timeout.
+func checkProvider(ctx context.Context, cfg *weather.Config, provider weather.Provider) error {
+	if cfg.ProviderTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.ProviderTimeout)
+		defer cancel()
+	}
+
+	_, err := provider.Current(ctx, probe, weather.Options{Units: cfg.Units, Lang: weather.DefaultLang})
+	return err
+}
+
+// report prints the outcome of a check and reports whether it passed.
+func report(w io.Writer, check string, err error) bool {
+	switch {
+	case err == nil:
+		fmt.Fprintf(w, "%s\tok\n", check)
+	case errors.Is(err, weather.ErrNoAPIKey):
+		fmt.Fprintf(w, "%s\trejected by the upstream\n", check)
+	default:
+		fmt.Fprintf(w, "%s\tfailed: %s\n", check, weather.Redact(err.Error()))
+	}
+
+	return err == nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+
+	"github.com/mpfrancis/weather"
+	weatherhttp "github.com/mpfrancis/weather/internal/http"
+	weatheros "github.com/mpfrancis/weather/internal/os"
+)
+
+// fetch looks the weather up once with the configured providers and prints the response /weather would return.
+// Besides the configuration flags, the flags are the optional query parameters of /weather and are validated the same way.
+// The units are those of the configuration.
+func fetch(ctx context.Context, args []string, out io.Writer) error {
+	fs, opts := configFlags("fetch", out)
+	forecast := fs.Int("forecast", -1, "day of the forecast to include, 0 is today, none by default")
+	lang := fs.String("lang", "", "language of the descriptions, e.g. es or pt_br (default en)")
+	ensemble := fs.Bool("ensemble", false, "combine the forecasts of every provider, requires --forecast")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 2 {
+		return fmt.Errorf("%w, fetch takes a city and a country", errUsage)
+	}
+
+	values := url.Values{"city": {fs.Arg(0)}, "country": {fs.Arg(1)}, "lang": {*lang}}
+	if *forecast >= 0 {
+		values.Set("forecast", strconv.Itoa(*forecast))
+	}
+	if *ensemble {
+		values.Set("ensemble", "true")
+	}
+
+	cfg, err := weatheros.Load(*opts)
+	if err != nil {
+		return err
+	}
+
+	q, err := weatherhttp.ParseQuery(values, cfg)
+	if err != nil {
+		return err
+	}
+
+	report, err := weather.NewService(cfg, weatherhttp.DefaultClient).Lookup(ctx, q)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report.Response)
+}
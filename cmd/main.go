@@ -1,22 +1,183 @@
+// Command cmd runs the weather API server and the tools to operate it.
+//
+//	cmd [serve] [config flags]
+//	cmd check-config [config flags]
+//	cmd fetch [--forecast 0] [--lang en] [--ensemble] [config flags] <city> <country>
+//	cmd cache stats|flush [--server http://localhost:10000]
+//
+// The commands are configured by flags, the environment and a config file, see internal/os.Load.
 package main
 
 import (
-	"github.com/mpfrancis/weather/internal/http"
-	"github.com/mpfrancis/weather/internal/os"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/mpfrancis/weather"
+	weatherhttp "github.com/mpfrancis/weather/internal/http"
+	weatheros "github.com/mpfrancis/weather/internal/os"
 	"github.com/sirupsen/logrus"
 )
 
+const usage = `Usage:
+  cmd [serve] [flags]          run the weather API server
+  cmd check-config [flags]     validate the configuration and verify the upstream API keys
+  cmd fetch [flags] <city> <country>
+                               look the weather up once and print the response of /weather
+  cmd cache stats|flush [--server URL]
+                               report or flush the caches of a running server
+
+Run "cmd <command> --help" for the flags of a command.
+`
+
+var errUsage = errors.New("Invalid arguments")
+
 func main() {
-	if err := run(); err != nil {
+	logrus.AddHook(weather.RedactHook{})
+
+	if err := run(interruptible(), os.Args[1:], os.Stdout); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+
+		if errors.Is(err, errUsage) {
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprint(os.Stderr, usage)
+			os.Exit(2)
+		}
 		logrus.Fatal(err)
 	}
 }
 
-func run() error {
-	cfg, err := os.GetConfig()
+// interruptible returns a context that is canceled on SIGINT or SIGTERM. A second signal terminates the process.
+func interruptible() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		logrus.Infof("Received %s, shutting down", sig)
+		signal.Stop(signals)
+		cancel()
+	}()
+
+	return ctx
+}
+
+// run executes the command named by the first argument, the server is run when there is none.
+func run(ctx context.Context, args []string, out io.Writer) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") && !isHelp(args[0]) {
+		return serve(ctx, args, out)
+	}
+
+	switch args[0] {
+	case "serve":
+		return serve(ctx, args[1:], out)
+	case "check-config":
+		return checkConfig(ctx, args[1:], weatherhttp.DefaultClient, out)
+	case "fetch":
+		return fetch(ctx, args[1:], out)
+	case "cache":
+		return cacheCommand(ctx, args[1:], out)
+	}
+
+	if isHelp(args[0]) {
+		fmt.Fprint(out, usage)
+		return nil
+	}
+
+	return fmt.Errorf("%w, unknown command %q", errUsage, args[0])
+}
+
+func isHelp(arg string) bool {
+	return arg == "help" || arg == "-h" || arg == "-help" || arg == "--help"
+}
+
+// configFlags returns the flag set of a command along with the configuration options its flags are recorded in.
+func configFlags(name string, out io.Writer) (*flag.FlagSet, *weatheros.Options) {
+	var opts weatheros.Options
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(out)
+	weatheros.RegisterFlags(fs, &opts)
+
+	return fs, &opts
+}
+
+// parseFlags parses the flags of a command, invalid flags are usage errors.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return fmt.Errorf("%w, %s", errUsage, err)
+	}
+
+	return nil
+}
+
+// serve runs the weather API server until it fails or the context is done, reloading its configuration on SIGHUP
+// or when its files change. Once the context is done, in-flight requests are given the shutdown timeout to complete.
+func serve(ctx context.Context, args []string, out io.Writer) error {
+	fs, opts := configFlags("serve", out)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if fs.NArg() > 0 {
+		return fmt.Errorf("%w, serve takes no arguments", errUsage)
+	}
+
+	cfg, err := weatheros.Load(*opts)
 	if err != nil {
 		return err
 	}
 
-	return http.NewServer(cfg, http.DefaultClient).ListenAndServe()
+	weatheros.ConfigureLogging(cfg)
+	s := weatherhttp.NewServer(cfg, weatherhttp.DefaultClient)
+	go weatheros.WatchConfig(ctx, *opts, cfg, 30*time.Second, func(cfg *weather.Config, err error) {
+		if err != nil {
+			s.RejectReload(err)
+			return
+		}
+
+		weatheros.ConfigureLogging(cfg)
+		s.Reload(cfg)
+	})
+
+	listening := make(chan error, 1)
+	go func() { listening <- s.ListenAndServe() }()
+
+	select {
+	case err := <-listening:
+		// The server failed to start, background work is stopped and the upstream usage saved all the same
+		if shutdownErr := s.Shutdown(context.Background()); shutdownErr != nil {
+			logrus.Warnf("Unable to shut down cleanly: %s", shutdownErr)
+		}
+		return err
+	case <-ctx.Done():
+	}
+
+	logrus.Infof("Waiting up to %s for in-flight requests", cfg.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	if err := s.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+
+	if err := <-listening; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	logrus.Info("Server stopped")
+	return nil
 }
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mpfrancis/weather"
+	weatherhttp "github.com/mpfrancis/weather/internal/http"
+	"github.com/stretchr/testify/assert"
+)
+
+// setenv sets the environment variables until the test ends.
+func setenv(t *testing.T, env map[string]string) func() {
+	for k, v := range env {
+		if err := os.Setenv(k, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return func() {
+		for k := range env {
+			os.Unsetenv(k)
+		}
+	}
+}
+
+// newOpenWeatherFake returns a local fake of open weather that only accepts the given key.
+func newOpenWeatherFake(key string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("appid") != key {
+			http.Error(w, `{"cod": 401, "message": "Invalid API key"}`, http.StatusUnauthorized)
+			return
+		}
+
+		fmt.Fprint(w, `{"coord": {"lon": -0.13, "lat": 51.51}, "name": "London", "sys": {"country": "GB"}, "main": {"temp": 12}}`)
+	}))
+}
+
+func TestCheckConfig(t *testing.T) {
+	upstream := newOpenWeatherFake("0123456789abcdef")
+	defer upstream.Close()
+
+	defer setenv(t, map[string]string{
+		"WEATHER_BASEURL":  upstream.URL,
+		"WEATHER_APIKEYS":  "0123456789abcdef:2,fedcba9876543210",
+		"CACHE_EXPIRATION": "",
+	})()
+
+	var out bytes.Buffer
+	assert.Equal(t, errCheckFailed, checkConfig(context.Background(), nil, http.DefaultClient, &out))
+	assert.Contains(t, out.String(), "WEATHER_APIKEYS              ****cdef:2,****3210:1\n")
+	assert.Contains(t, out.String(), "openweather key ****cdef  ok\n")
+	assert.Contains(t, out.String(), "openweather key ****3210  rejected by the upstream\n")
+	assert.NotContains(t, out.String(), "0123456789abcdef")
+
+	// Every invalid setting is reported
+	defer setenv(t, map[string]string{"WEATHER_APIKEYS": "0123456789abcdef", "CACHE_EXPIRATION": "5 minutes"})()
+	out.Reset()
+	assert.Equal(t, errCheckFailed, checkConfig(context.Background(), []string{"--units", "kelvin"}, http.DefaultClient, &out))
+	assert.Equal(t, "error: Invalid units, use: standard, metric, imperial. Default: metric\n"+
+		"error: CACHE_EXPIRATION \"5 minutes\" is invalid, please provide a duration such as 90s or 5m\n", out.String())
+
+	// Flags take precedence over the environment
+	out.Reset()
+	assert.Nil(t, checkConfig(context.Background(), []string{"--cache-expiration", "5m"}, http.DefaultClient, &out))
+	assert.Contains(t, out.String(), "CACHE_EXPIRATION             5m0s\n")
+}
+
+func TestFetchAndCache(t *testing.T) {
+	upstream := newOpenWeatherFake("0123456789abcdef")
+	defer upstream.Close()
+
+	defer setenv(t, map[string]string{
+		"WEATHER_BASEURL": upstream.URL,
+		"WEATHER_APIKEY":  "0123456789abcdef",
+	})()
+
+	ctx := context.Background()
+	var out bytes.Buffer
+	assert.Nil(t, run(ctx, []string{"fetch", "--units", "imperial", "London", "gb"}, &out))
+	assert.Contains(t, out.String(), `"location_name": "London, GB"`)
+	assert.Contains(t, out.String(), `"temperature": "12 °F"`)
+
+	err := run(ctx, []string{"fetch", "--forecast", "9", "London", "gb"}, &out)
+	assert.EqualError(t, err, "Query parameter 'forecast' is invalid, please provide a number between 0 and 6")
+
+	cfg := weather.Config{BaseURL: upstream.URL, APIKey: "0123456789abcdef", Units: weather.Metric, AdminToken: "admin-secret"}
+	s := weatherhttp.NewServer(&cfg, http.DefaultClient)
+	defer s.Shutdown(ctx)
+	server := httptest.NewServer(s.Handler)
+	defer server.Close()
+
+	if _, err := http.Get(server.URL + "/weather?city=London&country=gb"); err != nil {
+		t.Fatal(err)
+	}
+
+	out.Reset()
+	assert.Nil(t, run(ctx, []string{"cache", "stats", "--server", server.URL, "--token", "admin-secret"}, &out))
+	assert.Equal(t, "Responses     1\nHits          0\nMisses        1\nEvictions     0\nObservations  1\nForecasts     0\nCoordinates   1\n", out.String())
+
+	out.Reset()
+	assert.Nil(t, run(ctx, []string{"cache", "flush", "--server", server.URL, "--token", "admin-secret"}, &out))
+	assert.Equal(t, "Cache flushed\n", out.String())
+
+	out.Reset()
+	assert.Nil(t, run(ctx, []string{"cache", "stats", "--server", server.URL, "--token", "admin-secret"}, &out))
+	assert.Contains(t, out.String(), "Responses     0\n")
+
+	err = run(ctx, []string{"cache", "flush", "--server", server.URL, "--token", "guess"}, &out)
+	assert.EqualError(t, err, server.URL+" responded with status 401: Unauthorized")
+
+	// The token is read from the environment when not given, it is never printed in the usage
+	os.Setenv("SERVER_ADMIN_TOKEN", "admin-secret")
+	defer os.Unsetenv("SERVER_ADMIN_TOKEN")
+	out.Reset()
+	assert.Nil(t, run(ctx, []string{"cache", "flush", "--server", server.URL}, &out))
+	assert.Equal(t, "Cache flushed\n", out.String())
+
+	out.Reset()
+	assert.True(t, errors.Is(run(ctx, []string{"cache", "stats", "--help"}, &out), flag.ErrHelp))
+	assert.Contains(t, out.String(), "-token")
+	assert.NotContains(t, out.String(), "admin-secret")
+
+	assert.True(t, errors.Is(run(ctx, []string{"cache", "clear"}, &out), errUsage))
+	assert.True(t, errors.Is(run(ctx, []string{"deploy"}, &out), errUsage))
+}
+
+func TestServeShutdown(t *testing.T) {
+	usage := filepath.Join(t.TempDir(), "usage.json")
+	ctx, cancel := context.WithCancel(context.Background())
+
+	served := make(chan error, 1)
+	go func() {
+		served <- run(ctx, []string{"serve", "--providers", "openmeteo", "--server-address", "127.0.0.1:0", "--upstream-usage-file", usage}, ioutil.Discard)
+	}()
+
+	// The server stops cleanly once the context is done, and saves the upstream usage
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-served:
+		assert.Nil(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Server did not shut down")
+	}
+
+	_, err := os.Stat(usage)
+	assert.Nil(t, err)
+
+	// A server that cannot listen fails
+	err = run(context.Background(), []string{"--providers", "openmeteo", "--server-address", "127.0.0.1:-1"}, ioutil.Discard)
+	assert.NotNil(t, err)
+}
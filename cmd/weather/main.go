@@ -0,0 +1,237 @@
+// Command weather prints current conditions and forecasts in the terminal.
+//
+//	weather now [flags] <city> <country>
+//	weather forecast [--days 5] [flags] <city> <country>
+//	weather hourly [--hours 24] [flags] <city> <country>
+//
+// The providers are called directly with the configuration of the server's environment, see internal/os.GetConfig,
+// unless --server or WEATHER_SERVER names a running weather API server. Flags may follow the location.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/mpfrancis/weather"
+	"github.com/mpfrancis/weather/client"
+	weatheros "github.com/mpfrancis/weather/internal/os"
+	"github.com/sirupsen/logrus"
+)
+
+const envServer = "WEATHER_SERVER"
+
+const usage = `Usage:
+  weather now [flags] <city> <country>
+  weather forecast [--days 5] [flags] <city> <country>
+  weather hourly [--hours 24] [flags] <city> <country>
+
+Run "weather <command> --help" for the flags of a command.
+`
+
+var (
+	errUsage    = errors.New("Invalid arguments")
+	errLocation = errors.New("A city and a two letter ISO 3166 country code are required, e.g. weather now Bogota CO")
+	errDays     = errors.New("Flag --days must be between 1 and 7")
+	errHours    = errors.New("Flag --hours must be between 1 and 168")
+	errUnits    = errors.New("Flag --units is invalid, use: standard, metric, imperial")
+)
+
+// options are the flags shared by every command.
+type options struct {
+	server  string
+	json    bool
+	units   string
+	lang    string
+	noColor bool
+}
+
+func main() {
+	logrus.AddHook(weather.RedactHook{})
+	logrus.SetOutput(os.Stderr)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		cancel()
+	}()
+
+	if err := run(ctx, os.Args[1:], os.Stdout); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+
+		fmt.Fprintln(os.Stderr, "weather:", weather.Redact(err.Error()))
+		if errors.Is(err, errUsage) {
+			fmt.Fprint(os.Stderr, usage)
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
+
+// run executes the command named by the first argument.
+func run(ctx context.Context, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	var opts options
+	fs := flag.NewFlagSet("weather "+args[0], flag.ContinueOnError)
+	fs.StringVar(&opts.server, "server", os.Getenv(envServer), "URL of a running weather API server, the providers are called directly when empty")
+	fs.BoolVar(&opts.json, "json", false, "print JSON instead of tables and charts")
+	fs.StringVar(&opts.units, "units", "", "standard, metric or imperial (default WEATHER_UNITS, or metric with --server)")
+	fs.StringVar(&opts.lang, "lang", weather.DefaultLang, "language of the descriptions, e.g. es or pt_br")
+	fs.BoolVar(&opts.noColor, "no-color", os.Getenv("NO_COLOR") != "", "print charts without colors")
+
+	var days, hours int
+	switch args[0] {
+	case "now":
+	case "forecast":
+		fs.IntVar(&days, "days", 5, "number of days to forecast, today included")
+	case "hourly":
+		fs.IntVar(&hours, "hours", 24, "number of hours to forecast")
+	default:
+		return fmt.Errorf("%w, unknown command %q", errUsage, args[0])
+	}
+
+	positional, err := parseInterspersed(fs, args[1:])
+	if err != nil {
+		return err
+	}
+
+	if len(positional) != 2 {
+		return errLocation
+	}
+
+	loc := weather.Location{City: positional[0], Country: positional[1]}.Normalize()
+	if !loc.ValidCountry() {
+		return errLocation
+	}
+
+	if args[0] == "forecast" && (days < 1 || 7 < days) {
+		return errDays
+	}
+
+	if args[0] == "hourly" && (hours < 1 || 168 < hours) {
+		return errHours
+	}
+
+	src, units, err := newSource(opts)
+	if err != nil {
+		return err
+	}
+	r := renderer{out: out, units: units, color: !opts.noColor && isTerminal(out)}
+
+	switch args[0] {
+	case "now":
+		hr, err := src.current(ctx, loc)
+		if err != nil {
+			return err
+		}
+
+		if opts.json {
+			return printJSON(out, hr)
+		}
+		return r.current(hr)
+	case "forecast":
+		daily, zone, err := src.forecast(ctx, loc, days)
+		if err != nil {
+			return err
+		}
+
+		if opts.json {
+			return printJSON(out, daily)
+		}
+		return r.forecast(daily, zone)
+	}
+
+	hourly, zone, err := src.hourly(ctx, loc, hours)
+	if err != nil {
+		return err
+	}
+
+	if opts.json {
+		return printJSON(out, hourly)
+	}
+	return r.hourly(hourly, zone)
+}
+
+// parseInterspersed parses the flags wherever they appear among the arguments and returns the other arguments.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// newSource returns the source selected by the options, along with the units of the values it returns.
+func newSource(opts options) (source, weather.Unit, error) {
+	units := weather.Unit(strings.ToLower(opts.units))
+	if units != "" && !units.Valid() {
+		return nil, "", errUnits
+	}
+
+	if opts.server != "" {
+		if units == "" {
+			units = weather.Metric
+		}
+
+		return &serverSource{
+			client: client.New(opts.server),
+			opts:   []client.RequestOption{client.Units(units), client.Lang(opts.lang)},
+		}, units, nil
+	}
+
+	cfg, err := weatheros.GetConfig()
+	if err != nil {
+		return nil, "", err
+	}
+
+	if units != "" {
+		cfg.Units = units
+	}
+
+	return &directSource{
+		service: weather.NewService(cfg, &http.Client{Timeout: 30 * time.Second}, weather.WithLang(opts.lang)),
+		units:   cfg.Units,
+		lang:    opts.lang,
+	}, cfg.Units, nil
+}
+
+func printJSON(out io.Writer, v interface{}) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// isTerminal reports whether the output is a terminal, charts written anywhere else are not colored.
+func isTerminal(out io.Writer) bool {
+	f, ok := out.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mpfrancis/weather"
+	weatherhttp "github.com/mpfrancis/weather/internal/http"
+	"github.com/stretchr/testify/assert"
+)
+
+// newOpenMeteoFake returns a local fake of the open-meteo geocoding and forecast APIs.
+func newOpenMeteoFake() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/geocoding/search", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"results": [{"name": "Bogotá", "latitude": 4.61, "longitude": -74.08, "country_code": "CO"}]}`)
+	})
+	mux.HandleFunc("/v1/forecast", func(w http.ResponseWriter, r *http.Request) {
+		hourly := "[20, 18]"
+		if r.FormValue("temperature_unit") == "fahrenheit" {
+			hourly = "[68, 64.4]"
+		}
+
+		fmt.Fprintf(w, `{
+			"utc_offset_seconds": -18000,
+			"current": {"time": 1608843600, "temperature_2m": 20, "relative_humidity_2m": 37, "pressure_msl": 1025, "weather_code": 2},
+			"hourly": {"time": [1608843600, 1608847200], "temperature_2m": %s, "precipitation_probability": [40, 80], "precipitation": [0.2, 1.5], "weather_code": [2, 61]},
+			"daily": {
+				"time": [1608786000, 1608872400],
+				"weather_code": [61, 63],
+				"temperature_2m_max": [19.68, 17.74],
+				"temperature_2m_min": [8.89, 10.14],
+				"precipitation_sum": [6.42, 12.71],
+				"precipitation_probability_max": [97, 100]
+			}
+		}`, hourly)
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func TestRun(t *testing.T) {
+	server := newOpenMeteoFake()
+	defer server.Close()
+
+	env := map[string]string{
+		"WEATHER_PROVIDERS":           weather.OpenMeteoProvider,
+		"OPENMETEO_BASEURL":           server.URL + "/v1",
+		"OPENMETEO_GEOCODING_BASEURL": server.URL + "/geocoding",
+		envServer:                     "",
+	}
+	for k, v := range env {
+		if err := os.Setenv(k, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer func() {
+		for k := range env {
+			os.Unsetenv(k)
+		}
+	}()
+
+	ctx := context.Background()
+	var out bytes.Buffer
+	assert.Nil(t, run(ctx, []string{"now", "Bogota", "CO"}, &out))
+	assert.Contains(t, out.String(), "Location     Bogotá, CO\n")
+	assert.Contains(t, out.String(), "Temperature  20 °C\n")
+
+	// Days are shown in the location's time zone
+	forecast := `Day         Min    Max    Rain  Precip.  Wind     Conditions
+Thu Dec 24  9 °C   20 °C  97%   6.4 mm   0.0 m/s  light rain
+Fri Dec 25  10 °C  18 °C  100%  12.7 mm  0.0 m/s  moderate rain
+
+Temperature
+Thu 24 |========================================  9 °C to 20 °C
+Fri 25 |     ============================         10 °C to 18 °C
+
+Chance of rain
+Thu 24 |#######################################   97%, 6.4 mm
+Fri 25 |########################################  100%, 12.7 mm
+`
+	out.Reset()
+	assert.Nil(t, run(ctx, []string{"forecast", "Bogota", "CO", "--days", "2", "--no-color"}, &out))
+	assert.Equal(t, forecast, out.String())
+
+	out.Reset()
+	assert.Nil(t, run(ctx, []string{"hourly", "--hours", "1", "--json", "--units", "imperial", "Bogota", "CO"}, &out))
+	var hourly []weather.HourlyPoint
+	assert.Nil(t, json.Unmarshal(out.Bytes(), &hourly))
+	assert.Len(t, hourly, 1)
+	assert.Equal(t, 68.0, hourly[0].Temperature)
+
+	assert.Equal(t, errLocation, run(ctx, []string{"now", "Bogota"}, &out))
+	assert.Equal(t, errDays, run(ctx, []string{"forecast", "--days", "8", "Bogota", "CO"}, &out))
+
+	// A server is called instead with --server, its forecasts are also shown in the location's time zone
+	cfg := weather.Config{
+		Providers:             []string{weather.OpenMeteoProvider},
+		OpenMeteoURL:          server.URL + "/v1",
+		OpenMeteoGeocodingURL: server.URL + "/geocoding",
+		Units:                 weather.Metric,
+		CacheExpirationDur:    time.Minute,
+	}
+	weatherServer := httptest.NewServer(weatherhttp.NewWeatherHandler(&cfg, http.DefaultClient))
+	defer weatherServer.Close()
+
+	out.Reset()
+	assert.Nil(t, run(ctx, []string{"forecast", "--server", weatherServer.URL, "Bogota", "CO", "--days", "2", "--no-color"}, &out))
+	assert.Equal(t, forecast, out.String())
+
+	out.Reset()
+	assert.Nil(t, run(ctx, []string{"hourly", "--server", weatherServer.URL, "--hours", "3", "--no-color", "Bogota", "CO"}, &out))
+	assert.Contains(t, out.String(), "Thu 16:00  20 °C")
+	assert.Contains(t, out.String(), "Thu 17:00  18 °C")
+	assert.NotContains(t, out.String(), "21:00")
+}
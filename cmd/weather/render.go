@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mpfrancis/weather"
+)
+
+// chartWidth is the number of characters of the longest bar of a chart.
+const chartWidth = 40
+
+// ANSI colors of the charts.
+const (
+	colorReset  = "\x1b[0m"
+	colorBlue   = "\x1b[94m"
+	colorCyan   = "\x1b[36m"
+	colorGreen  = "\x1b[32m"
+	colorYellow = "\x1b[33m"
+	colorRed    = "\x1b[31m"
+)
+
+// renderer prints weather data as aligned tables followed by charts.
+type renderer struct {
+	out   io.Writer
+	units weather.Unit
+	color bool
+}
+
+// bar is a row of a chart, drawn from one value to another on the chart's scale.
+type bar struct {
+	label    string
+	from, to float64
+	text     string
+	color    string
+}
+
+// current prints the current conditions as a two column table.
+func (r renderer) current(hr *weather.HumanReadableResponse) error {
+	tw := tabwriter.NewWriter(r.out, 0, 4, 2, ' ', 0)
+	rows := [][2]string{
+		{"Location", hr.LocationName},
+		{"Temperature", hr.Temperature},
+		{"Conditions", hr.Cloudiness},
+		{"Wind", hr.Wind},
+		{"Pressure", hr.Pressure},
+		{"Humidity", hr.Humidity},
+		{"Sunrise", hr.Sunrise},
+		{"Sunset", hr.Sunset},
+		{"Coordinates", hr.GeoCoordinates},
+		{"Provider", hr.Provider},
+	}
+	for _, row := range rows {
+		if row[1] != "" {
+			fmt.Fprintf(tw, "%s\t%s\n", row[0], row[1])
+		}
+	}
+
+	return tw.Flush()
+}
+
+// forecast prints a table of the days followed by temperature and precipitation charts.
+func (r renderer) forecast(daily []weather.DailyForecast, zone *time.Location) error {
+	tw := tabwriter.NewWriter(r.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "Day\tMin\tMax\tRain\tPrecip.\tWind\tConditions")
+	for _, d := range daily {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%.0f%%\t%.1f mm\t%s\t%s\n",
+			d.Time.In(zone).Format("Mon Jan 2"), r.temperature(d.TempMin), r.temperature(d.TempMax),
+			d.PrecipitationProbability*100, d.Precipitation, r.wind(d.WindSpeed), d.Description)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	temperatures := make([]bar, len(daily))
+	rain := make([]bar, len(daily))
+	for i, d := range daily {
+		label := d.Time.In(zone).Format("Mon 02")
+		temperatures[i] = bar{
+			label: label,
+			from:  d.TempMin,
+			to:    d.TempMax,
+			text:  r.temperature(d.TempMin) + " to " + r.temperature(d.TempMax),
+			color: r.temperatureColor(d.TempMax),
+		}
+		rain[i] = bar{
+			label: label,
+			to:    d.PrecipitationProbability * 100,
+			text:  fmt.Sprintf("%.0f%%, %.1f mm", d.PrecipitationProbability*100, d.Precipitation),
+			color: colorCyan,
+		}
+	}
+
+	r.chart("Temperature", temperatures, '=')
+	r.chart("Chance of rain", rain, '#')
+
+	return nil
+}
+
+// hourly prints a table of the hours followed by temperature and precipitation charts.
+func (r renderer) hourly(hourly []weather.HourlyPoint, zone *time.Location) error {
+	tw := tabwriter.NewWriter(r.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "Time\tTemp.\tFeels like\tRain\tPrecip.\tWind\tConditions")
+	for _, h := range hourly {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%.0f%%\t%.1f mm\t%s\t%s\n",
+			h.Time.In(zone).Format("Mon 15:04"), r.temperature(h.Temperature), r.temperature(h.FeelsLike),
+			h.PrecipitationProbability*100, h.Precipitation, r.wind(h.WindSpeed), h.Description)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	// Temperature bars start at the lowest temperature
+	lowest := math.Inf(1)
+	for _, h := range hourly {
+		lowest = math.Min(lowest, h.Temperature)
+	}
+
+	temperatures := make([]bar, len(hourly))
+	rain := make([]bar, len(hourly))
+	for i, h := range hourly {
+		label := h.Time.In(zone).Format("Mon 15:04")
+		temperatures[i] = bar{label: label, from: lowest, to: h.Temperature, text: r.temperature(h.Temperature), color: r.temperatureColor(h.Temperature)}
+		rain[i] = bar{label: label, to: h.PrecipitationProbability * 100, text: fmt.Sprintf("%.0f%%", h.PrecipitationProbability*100), color: colorCyan}
+	}
+
+	r.chart("Temperature", temperatures, '=')
+	r.chart("Chance of rain", rain, '#')
+
+	return nil
+}
+
+// chart draws a horizontal bar per row, from its start to its end, on a scale shared by every row.
+func (r renderer) chart(title string, bars []bar, fill byte) {
+	if len(bars) == 0 {
+		return
+	}
+
+	min, max := math.Inf(1), math.Inf(-1)
+	labelWidth := 0
+	for _, b := range bars {
+		min = math.Min(min, b.from)
+		max = math.Max(max, b.to)
+		if len(b.label) > labelWidth {
+			labelWidth = len(b.label)
+		}
+	}
+
+	fmt.Fprintf(r.out, "\n%s\n", title)
+	for _, b := range bars {
+		start, end := scale(b.from, min, max), scale(b.to, min, max)
+		if end <= start {
+			end = start + 1
+		}
+
+		line := strings.Repeat(string(fill), end-start)
+		if r.color {
+			line = b.color + line + colorReset
+		}
+
+		fmt.Fprintf(r.out, "%-*s |%s%s%s %s\n", labelWidth, b.label, strings.Repeat(" ", start), line, strings.Repeat(" ", chartWidth+1-end), b.text)
+	}
+}
+
+// scale returns the position of the value on a scale of chartWidth characters from min to max.
+func scale(v, min, max float64) int {
+	if max <= min {
+		return 0
+	}
+
+	return int(math.Round((v - min) / (max - min) * chartWidth))
+}
+
+func (r renderer) temperature(t float64) string {
+	return fmt.Sprintf("%.0f %s", t, r.units.Symbol())
+}
+
+func (r renderer) wind(speed float64) string {
+	if r.units == weather.Imperial {
+		return fmt.Sprintf("%.1f mph", speed)
+	}
+
+	return fmt.Sprintf("%.1f m/s", speed)
+}
+
+// temperatureColor returns the color of a temperature, from blue below freezing to red above 30 °C.
+func (r renderer) temperatureColor(t float64) string {
+	celsius := t
+	switch r.units {
+	case weather.Standard:
+		celsius = t - 273.15
+	case weather.Imperial:
+		celsius = (t - 32) * 5 / 9
+	}
+
+	switch {
+	case celsius < 0:
+		return colorBlue
+	case celsius < 10:
+		return colorCyan
+	case celsius < 20:
+		return colorGreen
+	case celsius < 30:
+		return colorYellow
+	}
+
+	return colorRed
+}
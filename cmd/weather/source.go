@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mpfrancis/weather"
+	"github.com/mpfrancis/weather/client"
+)
+
+// maxServerDay is the last forecast day served by the /weather endpoint, today being day 0.
+const maxServerDay = 6
+
+// source looks the weather up, either by calling the providers directly or by calling a weather API server.
+// Forecasts are returned along with the time zone of the location, or UTC when it is unknown.
+type source interface {
+	current(ctx context.Context, loc weather.Location) (*weather.HumanReadableResponse, error)
+	forecast(ctx context.Context, loc weather.Location, days int) ([]weather.DailyForecast, *time.Location, error)
+	hourly(ctx context.Context, loc weather.Location, hours int) ([]weather.HourlyPoint, *time.Location, error)
+}
+
+// directSource calls the configured providers with the weather service.
+type directSource struct {
+	service *weather.Service
+	units   weather.Unit
+	lang    string
+}
+
+func (s *directSource) current(ctx context.Context, loc weather.Location) (*weather.HumanReadableResponse, error) {
+	report, err := s.service.Lookup(ctx, weather.Query{Location: loc, Units: s.units, Lang: s.lang, Forecast: -1})
+	if err != nil {
+		return nil, err
+	}
+
+	return report.Response, nil
+}
+
+func (s *directSource) forecast(ctx context.Context, loc weather.Location, days int) ([]weather.DailyForecast, *time.Location, error) {
+	f, zone, err := s.lookup(ctx, loc, days)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return f.Daily, zone, nil
+}
+
+func (s *directSource) hourly(ctx context.Context, loc weather.Location, hours int) ([]weather.HourlyPoint, *time.Location, error) {
+	f, zone, err := s.lookup(ctx, loc, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(f.Hourly) > hours {
+		return f.Hourly[:hours], zone, nil
+	}
+
+	return f.Hourly, zone, nil
+}
+
+// lookup returns the forecast along with the time zone of the location, taken from its current conditions.
+func (s *directSource) lookup(ctx context.Context, loc weather.Location, days int) (*weather.Forecast, *time.Location, error) {
+	obs, err := s.service.Current(ctx, loc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	f, err := s.service.Forecast(ctx, loc, days)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return f, time.FixedZone("", obs.UTCOffset), nil
+}
+
+// serverSource calls a weather API server with the client package.
+type serverSource struct {
+	client *client.Client
+	opts   []client.RequestOption
+}
+
+func (s *serverSource) current(ctx context.Context, loc weather.Location) (*weather.HumanReadableResponse, error) {
+	return s.client.Weather(ctx, loc, s.opts...)
+}
+
+func (s *serverSource) forecast(ctx context.Context, loc weather.Location, days int) ([]weather.DailyForecast, *time.Location, error) {
+	daily := make([]weather.DailyForecast, days)
+	var zone *time.Location
+	for day := range daily {
+		hr, err := s.day(ctx, loc, day)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		daily[day] = *hr.Forecast
+		zone = time.FixedZone("", hr.UTCOffset)
+	}
+
+	return daily, zone, nil
+}
+
+// hourly joins the hourly forecasts of the days served by the server, until there are enough hours or the provider
+// forecasts no more hours.
+func (s *serverSource) hourly(ctx context.Context, loc weather.Location, hours int) ([]weather.HourlyPoint, *time.Location, error) {
+	var (
+		points []weather.HourlyPoint
+		zone   *time.Location
+	)
+	for day := 0; day <= maxServerDay && len(points) < hours; day++ {
+		hr, err := s.day(ctx, loc, day)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		zone = time.FixedZone("", hr.UTCOffset)
+		if len(hr.Hourly) == 0 {
+			break
+		}
+		points = append(points, hr.Hourly...)
+	}
+
+	if len(points) > hours {
+		return points[:hours], zone, nil
+	}
+
+	return points, zone, nil
+}
+
+// day returns the server's response with the forecast for the day, from 0 for today.
+func (s *serverSource) day(ctx context.Context, loc weather.Location, day int) (*weather.HumanReadableResponse, error) {
+	hr, err := s.client.Weather(ctx, loc, append(s.opts, client.Day(day))...)
+	if err != nil {
+		return nil, err
+	}
+
+	if hr.Forecast == nil {
+		return nil, fmt.Errorf("Weather response has no forecast for day %d", day)
+	}
+
+	return hr, nil
+}
@@ -4,12 +4,92 @@ import "time"
 
 // Config is used for configuration and dependency injection.
 type Config struct {
+	// Providers are the names of the weather providers in order of preference.
+	// When a provider fails, times out or is over quota the request goes to the next one.
+	Providers []string
+	// ProviderTimeout bounds every call to a provider.
+	ProviderTimeout time.Duration
+	// OpenMeteoURL and OpenMeteoGeocodingURL are the base URLs of the open-meteo forecast and geocoding APIs.
+	OpenMeteoURL          string
+	OpenMeteoGeocodingURL string
+
 	BaseURL            string
 	APIKey             string
+	APIKeys            []APIKey
+	APIKeysFile        string
+	APIKeyCooldown     time.Duration
 	ServerAddress      string
 	CacheExpiration    string
 	CacheExpirationDur time.Duration
+	CacheTTLs          map[DataType]TTLPolicy
 	Units              Unit
+
+	// APIKeyFile and APIKeyCommand name the secret source APIKey was read from, a file or a command printing it.
+	// APIKeyRefresh is how often the secret is read again so that a rotated one is picked up, zero disables refreshing.
+	APIKeyFile    string
+	APIKeyCommand string
+	APIKeyRefresh time.Duration
+
+	// LogLevel is the minimum level of the messages logged, e.g. info or debug.
+	// LogFormat is how the messages are written: json, one object per line, or text.
+	LogLevel  string
+	LogFormat string
+
+	// ShutdownTimeout is how long the server waits for in-flight requests to complete when it is shut down.
+	ShutdownTimeout time.Duration
+
+	// AdminToken is the bearer token required by the /admin endpoints, they are disabled when it is empty.
+	AdminToken string
+
+	// Locations are named locations, requests may give a name in place of a city, zip code or coordinates.
+	// Names are lower case.
+	Locations map[string]Location
+
+	// UpstreamCallsPerMinute and UpstreamCallsPerMonth are the calls allowed by the open weather plan, zero means unlimited.
+	UpstreamCallsPerMinute int
+	UpstreamCallsPerMonth  int
+	// UpstreamQuotaReserve is the share of each limit reserved for essential calls, non-essential work is shed beyond it.
+	UpstreamQuotaReserve float64
+	// UpstreamUsageFile is where upstream usage is persisted across restarts, usage is not persisted when it is empty.
+	UpstreamUsageFile string
+
+	// PrefetchTopN is the number of most requested locations that are refreshed before their cache entries expire.
+	// Prefetching is disabled when it is zero.
+	PrefetchTopN int
+	// PrefetchLead is how long before expiry a cache entry is refreshed.
+	PrefetchLead time.Duration
+	// PrefetchShare is the share of UpstreamCallsPerMinute that prefetching may use.
+	PrefetchShare float64
+}
+
+// UsesProvider reports whether the named provider is one of the configured providers.
+func (c *Config) UsesProvider(name string) bool {
+	for _, p := range c.Providers {
+		if p == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Keys returns the upstream API keys, falling back to APIKey when no weighted keys are configured.
+func (c *Config) Keys() []APIKey {
+	if len(c.APIKeys) > 0 {
+		return c.APIKeys
+	}
+
+	return []APIKey{{Key: c.APIKey, Weight: 1}}
+}
+
+// CachePolicy returns the cache policy for the given data type.
+// Data types without a policy of their own are cached for CacheExpirationDur.
+func (c *Config) CachePolicy(t DataType) TTLPolicy {
+	if p, ok := c.CacheTTLs[t]; ok {
+		return p
+	}
+
+	return TTLPolicy{TTL: c.CacheExpirationDur}
 }
 
 // Unit provides a type for setting the unit of the open weather API.
@@ -18,10 +98,20 @@ type Unit string
 // List of unit types.
 const (
 	Metric   Unit = "metric"
-	Standard Unit = "imperial"
-	Imperial Unit = "standard"
+	Standard Unit = "standard"
+	Imperial Unit = "imperial"
 )
 
+// Valid reports whether the unit is one of the supported unit types.
+func (u Unit) Valid() bool {
+	switch u {
+	case Metric, Standard, Imperial:
+		return true
+	}
+
+	return false
+}
+
 // Symbol returns the symbol used for the given unit type.
 func (u Unit) Symbol() string {
 	switch u {
@@ -0,0 +1,136 @@
+package weather
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Disagreement thresholds in metric units, values of different providers further apart than these are flagged.
+const (
+	disagreeTemperature  = 3.0 // °C
+	disagreeWindSpeed    = 5.0 // m/s
+	disagreeRainLikely   = 0.5
+	disagreeRainUnlikely = 0.2
+)
+
+// List of ensemble confidence levels.
+const (
+	ConfidenceHigh   = "high"
+	ConfidenceMedium = "medium"
+	ConfidenceLow    = "low"
+)
+
+// ProviderForecast is the daily forecast of a single provider.
+type ProviderForecast struct {
+	Provider string
+	Daily    []DailyForecast
+}
+
+// Spread is the consensus of the values reported by several providers along with their range.
+type Spread struct {
+	Value float64 `json:"value"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+}
+
+// EnsembleDaily is a daily forecast combined from several providers.
+type EnsembleDaily struct {
+	Time                     time.Time `json:"time"`
+	Providers                []string  `json:"providers"`
+	TempMin                  Spread    `json:"temp_min"`
+	TempMax                  Spread    `json:"temp_max"`
+	PrecipitationProbability Spread    `json:"precipitation_probability"`
+	Precipitation            Spread    `json:"precipitation"`
+	WindSpeed                Spread    `json:"wind_speed"`
+	Confidence               string    `json:"confidence"`
+	Disagreements            []string  `json:"disagreements,omitempty"`
+}
+
+// CombineForecasts combines the forecasts of several providers day by day.
+// Days are matched by position, a day is combined from the providers that forecast it.
+// The consensus value is the median, the confidence is high when at least two providers agree on everything.
+func CombineForecasts(forecasts []ProviderForecast, units Unit) []EnsembleDaily {
+	days := 0
+	for _, f := range forecasts {
+		if len(f.Daily) > days {
+			days = len(f.Daily)
+		}
+	}
+
+	ensemble := make([]EnsembleDaily, days)
+	for i := range ensemble {
+		var daily []DailyForecast
+		e := &ensemble[i]
+		for _, f := range forecasts {
+			if i < len(f.Daily) {
+				daily = append(daily, f.Daily[i])
+				e.Providers = append(e.Providers, f.Provider)
+			}
+		}
+
+		e.Time = daily[0].Time
+		e.TempMin = spreadOf(daily, func(d DailyForecast) float64 { return d.TempMin })
+		e.TempMax = spreadOf(daily, func(d DailyForecast) float64 { return d.TempMax })
+		e.PrecipitationProbability = spreadOf(daily, func(d DailyForecast) float64 { return d.PrecipitationProbability })
+		e.Precipitation = spreadOf(daily, func(d DailyForecast) float64 { return d.Precipitation })
+		e.WindSpeed = spreadOf(daily, func(d DailyForecast) float64 { return d.WindSpeed })
+
+		if e.PrecipitationProbability.Max >= disagreeRainLikely && e.PrecipitationProbability.Min <= disagreeRainUnlikely {
+			e.Disagreements = append(e.Disagreements, "providers disagree on rain")
+		}
+
+		if e.TempMax.Max-e.TempMax.Min > temperatureDelta(disagreeTemperature, units) || e.TempMin.Max-e.TempMin.Min > temperatureDelta(disagreeTemperature, units) {
+			e.Disagreements = append(e.Disagreements, "providers disagree on temperature")
+		}
+
+		if e.WindSpeed.Max-e.WindSpeed.Min > windSpeed(disagreeWindSpeed, units) {
+			e.Disagreements = append(e.Disagreements, "providers disagree on wind")
+		}
+
+		switch {
+		case len(e.Disagreements) > 1:
+			e.Confidence = ConfidenceLow
+		case len(e.Disagreements) == 1 || len(daily) == 1:
+			e.Confidence = ConfidenceMedium
+		default:
+			e.Confidence = ConfidenceHigh
+		}
+	}
+
+	return ensemble
+}
+
+// spreadOf returns the median and range of a value of the forecasts.
+func spreadOf(daily []DailyForecast, value func(DailyForecast) float64) Spread {
+	values := make([]float64, len(daily))
+	for i, d := range daily {
+		values[i] = value(d)
+	}
+	sort.Float64s(values)
+
+	median := values[len(values)/2]
+	if len(values)%2 == 0 {
+		median = (values[len(values)/2-1] + median) / 2
+	}
+
+	return Spread{Value: math.Round(median*100) / 100, Min: values[0], Max: values[len(values)-1]}
+}
+
+// temperatureDelta converts a difference in celsius to the units, kelvin differences equal celsius differences.
+func temperatureDelta(celsius float64, units Unit) float64 {
+	if units == Imperial {
+		return celsius * 9 / 5
+	}
+
+	return celsius
+}
+
+// windSpeed converts a speed in meters per second to the units, imperial speeds are in miles per hour.
+func windSpeed(ms float64, units Unit) float64 {
+	if units == Imperial {
+		return ms * 2.23694
+	}
+
+	return ms
+}
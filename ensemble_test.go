@@ -0,0 +1,94 @@
+package weather
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type combineForecastsCase struct {
+	name                  string
+	units                 Unit
+	forecasts             []ProviderForecast
+	expectedConfidence    string
+	expectedDisagreements []string
+}
+
+func day(min, max, pop, wind float64) DailyForecast {
+	return DailyForecast{TempMin: min, TempMax: max, PrecipitationProbability: pop, WindSpeed: wind}
+}
+
+func TestCombineForecasts(t *testing.T) {
+	cases := []combineForecastsCase{
+		{
+			name:  "agreement",
+			units: Metric,
+			forecasts: []ProviderForecast{
+				{Provider: "a", Daily: []DailyForecast{day(8, 19, 0.1, 3)}},
+				{Provider: "b", Daily: []DailyForecast{day(9, 20, 0.15, 4)}},
+			},
+			expectedConfidence: ConfidenceHigh,
+		},
+		{
+			name:  "single provider",
+			units: Metric,
+			forecasts: []ProviderForecast{
+				{Provider: "a", Daily: []DailyForecast{day(8, 19, 0.1, 3)}},
+			},
+			expectedConfidence: ConfidenceMedium,
+		},
+		{
+			name:  "rain",
+			units: Metric,
+			forecasts: []ProviderForecast{
+				{Provider: "a", Daily: []DailyForecast{day(8, 19, 0.1, 3)}},
+				{Provider: "b", Daily: []DailyForecast{day(9, 20, 0.8, 4)}},
+			},
+			expectedConfidence:    ConfidenceMedium,
+			expectedDisagreements: []string{"providers disagree on rain"},
+		},
+		{
+			name:  "temperature and wind",
+			units: Metric,
+			forecasts: []ProviderForecast{
+				{Provider: "a", Daily: []DailyForecast{day(8, 19, 0.1, 3)}},
+				{Provider: "b", Daily: []DailyForecast{day(9, 24, 0.1, 10)}},
+			},
+			expectedConfidence:    ConfidenceLow,
+			expectedDisagreements: []string{"providers disagree on temperature", "providers disagree on wind"},
+		},
+		{
+			name:  "imperial thresholds",
+			units: Imperial,
+			forecasts: []ProviderForecast{
+				{Provider: "a", Daily: []DailyForecast{day(46, 66, 0.1, 7)}},
+				{Provider: "b", Daily: []DailyForecast{day(48, 71, 0.1, 15)}},
+			},
+			expectedConfidence: ConfidenceHigh,
+		},
+	}
+
+	for _, c := range cases {
+		ensemble := CombineForecasts(c.forecasts, c.units)
+		assert.Len(t, ensemble, 1, c.name)
+		assert.Equal(t, c.expectedConfidence, ensemble[0].Confidence, c.name)
+		assert.Equal(t, c.expectedDisagreements, ensemble[0].Disagreements, c.name)
+	}
+}
+
+func TestCombineForecastsSpread(t *testing.T) {
+	ensemble := CombineForecasts([]ProviderForecast{
+		{Provider: "a", Daily: []DailyForecast{day(8, 19, 0.1, 3), day(7, 18, 0.2, 2)}},
+		{Provider: "b", Daily: []DailyForecast{day(9, 20, 0.3, 4)}},
+		{Provider: "c", Daily: []DailyForecast{day(10, 22, 0.2, 5)}},
+	}, Metric)
+
+	assert.Len(t, ensemble, 2)
+	assert.Equal(t, []string{"a", "b", "c"}, ensemble[0].Providers)
+	assert.Equal(t, Spread{Value: 20, Min: 19, Max: 22}, ensemble[0].TempMax)
+	assert.Equal(t, Spread{Value: 0.2, Min: 0.1, Max: 0.3}, ensemble[0].PrecipitationProbability)
+
+	// Days forecast by fewer providers are combined from those providers only
+	assert.Equal(t, []string{"a"}, ensemble[1].Providers)
+	assert.Equal(t, Spread{Value: 18, Min: 18, Max: 18}, ensemble[1].TempMax)
+}
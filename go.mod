@@ -3,7 +3,12 @@ module github.com/mpfrancis/weather
 go 1.15
 
 require (
+	github.com/BurntSushi/toml v1.2.1
 	github.com/patrickmn/go-cache v2.1.0+incompatible
+	github.com/prometheus/client_golang v1.12.2
 	github.com/sirupsen/logrus v1.7.0
-	github.com/stretchr/testify v1.3.0
+	github.com/stretchr/testify v1.6.0
+	golang.org/x/sys v0.7.0 // indirect
+	google.golang.org/protobuf v1.27.1 // indirect
+	gopkg.in/yaml.v3 v3.0.1
 )
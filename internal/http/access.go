@@ -0,0 +1,163 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mpfrancis/weather"
+	"github.com/sirupsen/logrus"
+)
+
+// requestIDHeader is the header carrying the ID of a request, given by the client or assigned by the server.
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength is the length beyond which the request ID given by a client is replaced.
+const maxRequestIDLength = 128
+
+// accessEntry collects what the handlers of a request report for its access log line.
+type accessEntry struct {
+	mu            sync.Mutex
+	location      string
+	cacheHit      bool
+	upstreamCalls int
+}
+
+type accessEntryKey struct{}
+
+// accessEntryFrom returns the access log entry of the request the context belongs to, nil outside of a logged request.
+func accessEntryFrom(ctx context.Context) *accessEntry {
+	e, _ := ctx.Value(accessEntryKey{}).(*accessEntry)
+	return e
+}
+
+// setLocation records the normalized location of the request.
+func (e *accessEntry) setLocation(loc weather.Location) {
+	if e == nil {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.location = loc.String()
+}
+
+// setCacheHit records that the request was answered from the response cache.
+func (e *accessEntry) setCacheHit() {
+	if e == nil {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.cacheHit = true
+}
+
+// addUpstreamCall counts an upstream call made for the request.
+func (e *accessEntry) addUpstreamCall() {
+	if e == nil {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.upstreamCalls++
+}
+
+// accessLog logs every request once it is served, along with its status, latency, location, whether it was answered
+// from the response cache and the upstream calls it made.
+// Requests keep the ID given by the client in the X-Request-ID header, or are assigned one. It is sent back in the response,
+// and is in the request_id field of the access log line and of every log line of the request, upstream calls included.
+func accessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, id)
+
+		entry := &accessEntry{}
+		ctx := context.WithValue(weather.WithRequestID(r.Context(), id), accessEntryKey{}, entry)
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		entry.mu.Lock()
+		fields := logrus.Fields{
+			"method":         r.Method,
+			"path":           r.URL.Path,
+			"status":         rec.Status(),
+			"latency":        time.Since(start).Seconds(),
+			"cache_hit":      entry.cacheHit,
+			"upstream_calls": entry.upstreamCalls,
+		}
+		if entry.location != "" {
+			fields["location"] = entry.location
+		}
+		entry.mu.Unlock()
+
+		weather.Logger(ctx).WithFields(fields).Info("Request served")
+	})
+}
+
+// validRequestID reports whether a request ID given by a client can be kept: it is not empty nor too long, and only has
+// printable ASCII characters other than spaces, so that it cannot forge log lines.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// newRequestID returns a random request ID of 32 hexadecimal digits, or the current time in nanoseconds in hexadecimal
+// in the unlikely event that no random bytes can be read.
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// loggingClient logs the upstream calls made with a client at the debug level, with the ID of the request they serve,
+// and counts them in the access log entry of the request.
+type loggingClient struct {
+	client Clienter
+}
+
+// Do makes the call with the underlying client.
+func (c loggingClient) Do(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := c.client.Do(req)
+
+	ctx := req.Context()
+	accessEntryFrom(ctx).addUpstreamCall()
+
+	log := weather.Logger(ctx).WithFields(logrus.Fields{
+		"endpoint": req.URL.Host + req.URL.Path,
+		"latency":  time.Since(start).Seconds(),
+	})
+	if err != nil {
+		log.Warnf("Upstream call failed: %s", weather.RedactError(err))
+		return resp, err
+	}
+	log.WithField("status", resp.StatusCode).Debug("Upstream call")
+
+	return resp, nil
+}
@@ -0,0 +1,91 @@
+package http
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mpfrancis/weather"
+	"github.com/mpfrancis/weather/internal/mock"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccessLog(t *testing.T) {
+	hook := test.NewGlobal()
+	defer logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+	level := logrus.GetLevel()
+	logrus.SetLevel(logrus.DebugLevel)
+	defer logrus.SetLevel(level)
+
+	cfg := weather.Config{BaseURL: "http://upstream/data/2.5", APIKey: "key", Units: weather.Metric}
+	var mockClient mock.Client
+	mockClient.GetFn = func(url string) (resp *http.Response, err error) {
+		body := `{"coord": {"lon": -74.08, "lat": 4.61}, "name": "Bogotá", "sys": {"country": "CO"}}`
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(body))}, nil
+	}
+	s := NewServer(&cfg, &mockClient)
+	server := httptest.NewServer(s.Handler)
+	defer server.Close()
+
+	get := func(path, id string) *http.Response {
+		req, err := http.NewRequest("GET", server.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id != "" {
+			req.Header.Set(requestIDHeader, id)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	// The request ID of the client is propagated to the upstream call and the access log
+	resp := get("/weather?city=Bogota&country=co", "abc-123")
+	assert.Equal(t, "abc-123", resp.Header.Get(requestIDHeader))
+
+	entries := hook.AllEntries()
+	assert.Equal(t, 2, len(entries))
+	assert.Equal(t, "Upstream call", entries[0].Message)
+	assert.Equal(t, logrus.Fields{"request_id": "abc-123", "endpoint": "upstream/data/2.5/weather", "status": 200, "latency": entries[0].Data["latency"]}, entries[0].Data)
+	assert.Equal(t, "Request served", entries[1].Message)
+	assert.Equal(t, logrus.InfoLevel, entries[1].Level)
+	assert.Equal(t, logrus.Fields{
+		"request_id":     "abc-123",
+		"method":         "GET",
+		"path":           "/weather",
+		"location":       "bogota,CO",
+		"status":         200,
+		"latency":        entries[1].Data["latency"],
+		"cache_hit":      false,
+		"upstream_calls": 1,
+	}, entries[1].Data)
+
+	// Requests without a valid ID are assigned one, cached responses make no upstream call
+	hook.Reset()
+	resp = get("/weather?city=Bogota&country=co", "bad id")
+	id := resp.Header.Get(requestIDHeader)
+	assert.Equal(t, 32, len(id))
+
+	entry := hook.LastEntry()
+	assert.Equal(t, 1, len(hook.AllEntries()))
+	assert.Equal(t, id, entry.Data["request_id"])
+	assert.Equal(t, true, entry.Data["cache_hit"])
+	assert.Equal(t, 0, entry.Data["upstream_calls"])
+
+	// Every route is logged, without a location when it has none
+	hook.Reset()
+	get("/livez", "")
+	entry = hook.LastEntry()
+	assert.Equal(t, "/livez", entry.Data["path"])
+	assert.NotContains(t, entry.Data, "location")
+	assert.NotEqual(t, id, entry.Data["request_id"])
+}
@@ -0,0 +1,80 @@
+package http
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// adminOnly serves the requests to an admin endpoint that carry the admin token of the weather handler's config
+// in an "Authorization: Bearer" header. Other requests get 401 Unauthorized, and every request gets 403 Forbidden
+// when no admin token is configured.
+func adminOnly(weather *WeatherHandler, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := weather.config().AdminToken
+		if token == "" {
+			http.Error(w, "Admin endpoints are disabled, no admin token is configured", http.StatusForbidden)
+			return
+		}
+
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "Bearer ") || subtle.ConstantTimeCompare([]byte(auth[len("Bearer "):]), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// UsageHandler is the handler for the /admin/usage endpoint, reporting the upstream calls made by the weather handler.
+type UsageHandler struct {
+	weather *WeatherHandler
+}
+
+// NewUsageHandler returns a new instance of the usage http handler.
+func NewUsageHandler(weather *WeatherHandler) *UsageHandler {
+	return &UsageHandler{weather: weather}
+}
+
+// ServeHTTP handles a usage request.
+func (h *UsageHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(h.weather.Usage()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// CacheHandler is the handler for the /admin/cache endpoint.
+// GET reports the statistics of the weather handler's caches and DELETE flushes them.
+type CacheHandler struct {
+	weather *WeatherHandler
+}
+
+// NewCacheHandler returns a new instance of the cache http handler.
+func NewCacheHandler(weather *WeatherHandler) *CacheHandler {
+	return &CacheHandler{weather: weather}
+}
+
+// ServeHTTP handles a cache request.
+func (h *CacheHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		if err := json.NewEncoder(w).Encode(h.weather.CacheStats()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	case http.MethodDelete:
+		h.weather.FlushCache()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, DELETE")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
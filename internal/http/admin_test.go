@@ -0,0 +1,95 @@
+package http
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mpfrancis/weather"
+	"github.com/mpfrancis/weather/internal/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheHandler(t *testing.T) {
+	cfg := weather.Config{Units: weather.Metric, CacheExpirationDur: time.Minute}
+	var mockClient mock.Client
+	mockClient.GetFn = func(url string) (resp *http.Response, err error) {
+		body := `{"coord": {"lon": -74.08, "lat": 4.61}, "name": "Bogotá", "sys": {"country": "CO"}}`
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(body))}, nil
+	}
+	weatherHandler := NewWeatherHandler(&cfg, &mockClient)
+	handler := NewCacheHandler(weatherHandler)
+
+	serve := func(h http.Handler, method, url string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// The second request is answered from the response cache
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, 200, serve(weatherHandler, "GET", "/weather?city=Bogota&country=co").Code)
+	}
+
+	rr := serve(handler, "GET", "/admin/cache")
+	assert.Equal(t, 200, rr.Code)
+	var stats map[string]int
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &stats))
+	assert.Equal(t, map[string]int{"responses": 1, "hits": 1, "misses": 1, "evictions": 0, "observations": 1, "forecasts": 0, "coordinates": 1}, stats)
+
+	assert.Equal(t, 204, serve(handler, "DELETE", "/admin/cache").Code)
+	assert.Equal(t, CacheStats{Hits: 1, Misses: 1, CacheStats: weather.CacheStats{Coordinates: 1}}, weatherHandler.CacheStats())
+
+	rr = serve(handler, "POST", "/admin/cache")
+	assert.Equal(t, 405, rr.Code)
+	assert.Equal(t, "GET, DELETE", rr.Header().Get("Allow"))
+}
+
+func TestAdminOnly(t *testing.T) {
+	cfg := weather.Config{Units: weather.Metric}
+	var mockClient mock.Client
+	weatherHandler := NewWeatherHandler(&cfg, &mockClient)
+	handler := adminOnly(weatherHandler, NewCacheHandler(weatherHandler))
+
+	serve := func(method, auth string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, "/admin/cache", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Admin endpoints are disabled without a token
+	rr := serve("DELETE", "Bearer ")
+	assert.Equal(t, 403, rr.Code)
+	assert.Equal(t, "Admin endpoints are disabled, no admin token is configured\n", rr.Body.String())
+
+	reloaded := cfg
+	reloaded.AdminToken = "admin-secret"
+	weatherHandler.Reload(&reloaded)
+
+	// Requests without the token are rejected
+	for _, auth := range []string{"", "Bearer guess", "admin-secret", "Basic admin-secret"} {
+		rr = serve("DELETE", auth)
+		assert.Equal(t, 401, rr.Code, auth)
+		assert.Equal(t, `Bearer realm="admin"`, rr.Header().Get("WWW-Authenticate"))
+	}
+
+	assert.Equal(t, 204, serve("DELETE", "Bearer admin-secret").Code)
+	assert.Equal(t, 200, serve("GET", "Bearer admin-secret").Code)
+}
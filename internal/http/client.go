@@ -1,11 +1,13 @@
 package http
 
-import "net/http"
+import (
+	"net/http"
+
+	"github.com/mpfrancis/weather"
+)
 
 // Clienter is an interface for net/http Client
-type Clienter interface {
-	Get(url string) (resp *http.Response, err error)
-}
+type Clienter = weather.Client
 
 // DefaultClient serves as the default http client for the http layer
 var DefaultClient = &http.Client{}
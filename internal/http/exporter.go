@@ -0,0 +1,125 @@
+package http
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/mpfrancis/weather"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// ExporterHandler is the handler for the /metrics/weather endpoint. It publishes the weather at the configured locations
+// as Prometheus gauges labeled with the location name, so that it can be charted next to other sensors.
+// Scrapes only read the data cached by the weather service, the prefetcher refreshes it within its share of the upstream
+// budget. The last known values are published while the data of a location is not cached.
+type ExporterHandler struct {
+	weather *WeatherHandler
+	metrics http.Handler
+
+	temperature   *prometheus.GaugeVec
+	humidity      *prometheus.GaugeVec
+	pressure      *prometheus.GaugeVec
+	windSpeed     *prometheus.GaugeVec
+	precipitation *prometheus.GaugeVec
+	observed      *prometheus.GaugeVec
+	up            *prometheus.GaugeVec
+
+	// mu serializes scrapes, published holds the units of the values published for every location.
+	mu        sync.Mutex
+	published map[string]weather.Unit
+}
+
+// NewExporterHandler returns a new instance of the weather exporter http handler.
+func NewExporterHandler(h *WeatherHandler) *ExporterHandler {
+	e := &ExporterHandler{
+		weather:       h,
+		temperature:   newGaugeVec("weather_temperature", "Temperature, in kelvin, celsius or fahrenheit by units.", "location", "units"),
+		humidity:      newGaugeVec("weather_humidity_percent", "Relative humidity.", "location"),
+		pressure:      newGaugeVec("weather_pressure_hpa", "Atmospheric pressure at sea level.", "location"),
+		windSpeed:     newGaugeVec("weather_wind_speed", "Wind speed, in meters per second or miles per hour by units.", "location", "units"),
+		precipitation: newGaugeVec("weather_precipitation_probability", "Probability of precipitation today, from 0 to 1.", "location"),
+		observed:      newGaugeVec("weather_observation_timestamp_seconds", "Time of the upstream observation of the published values.", "location"),
+		up:            newGaugeVec("weather_exporter_up", "Whether the values of the location are cached, the last known values are published otherwise.", "location"),
+		published:     make(map[string]weather.Unit),
+	}
+
+	r := prometheus.NewRegistry()
+	r.MustRegister(e.temperature, e.humidity, e.pressure, e.windSpeed, e.precipitation, e.observed, e.up)
+	e.metrics = promhttp.HandlerFor(r, promhttp.HandlerOpts{})
+
+	return e
+}
+
+// newGaugeVec returns a gauge with the given label names.
+func newGaugeVec(name, help string, labels ...string) *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: name, Help: help}, labels)
+}
+
+// ServeHTTP handles a scrape of the weather values.
+func (e *ExporterHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.refresh()
+	e.metrics.ServeHTTP(w, r)
+}
+
+// refresh updates the gauges of every configured location from the cached data, it never calls the upstream.
+// Locations that are no longer configured are removed.
+func (e *ExporterHandler) refresh() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	cfg := e.weather.config()
+	for name, units := range e.published {
+		if _, ok := cfg.Locations[name]; !ok {
+			e.remove(name, units)
+		}
+	}
+
+	for name, loc := range cfg.Locations {
+		units, ok := e.update(name, loc)
+		if !ok {
+			continue
+		}
+
+		// Values published in other units, before the units were reloaded, are replaced
+		if old, ok := e.published[name]; ok && old != units {
+			e.temperature.DeleteLabelValues(name, string(old))
+			e.windSpeed.DeleteLabelValues(name, string(old))
+		}
+		e.published[name] = units
+	}
+}
+
+// update sets the gauges of the location and reports whether its current conditions are cached, along with their units.
+// The probability of precipitation comes from today's forecast, it keeps its last value when the forecast is not cached.
+func (e *ExporterHandler) update(name string, loc weather.Location) (weather.Unit, bool) {
+	obs, ok := e.weather.service.CachedCurrent(loc)
+	if !ok {
+		e.up.WithLabelValues(name).Set(0)
+		return "", false
+	}
+
+	units := string(obs.Units)
+	e.temperature.WithLabelValues(name, units).Set(obs.Temperature)
+	e.humidity.WithLabelValues(name).Set(obs.Humidity)
+	e.pressure.WithLabelValues(name).Set(obs.Pressure)
+	e.windSpeed.WithLabelValues(name, units).Set(obs.WindSpeed)
+	e.observed.WithLabelValues(name).Set(float64(obs.Time.Unix()))
+	e.up.WithLabelValues(name).Set(1)
+
+	if f, ok := e.weather.service.CachedForecast(loc, 1); ok && len(f.Daily) > 0 {
+		e.precipitation.WithLabelValues(name).Set(f.Daily[0].PrecipitationProbability)
+	}
+
+	return obs.Units, true
+}
+
+// remove stops publishing the location.
+func (e *ExporterHandler) remove(name string, units weather.Unit) {
+	e.temperature.DeleteLabelValues(name, string(units))
+	e.windSpeed.DeleteLabelValues(name, string(units))
+	for _, g := range []*prometheus.GaugeVec{e.humidity, e.pressure, e.precipitation, e.observed, e.up} {
+		g.DeleteLabelValues(name)
+	}
+	delete(e.published, name)
+}
@@ -0,0 +1,93 @@
+package http
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mpfrancis/weather"
+	"github.com/mpfrancis/weather/internal/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExporterHandler(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	var mockClient mock.Client
+	mockClient.GetFn = func(url string) (resp *http.Response, err error) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+
+		body := `{"coord": {"lon": -74.08, "lat": 4.61}, "name": "Bogotá", "sys": {"country": "CO"}, "dt": 1608843600, ` +
+			`"main": {"temp": 14.5, "pressure": 1027, "humidity": 72}, "wind": {"speed": 2.1}}`
+		if strings.Contains(url, "/onecall") {
+			body = `{"daily": [{"dt": 1608825600, "temp": {"min": 9, "max": 19}, "pop": 0.4}]}`
+		}
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(body))}, nil
+	}
+
+	// Non-essential calls, such as the prefetcher's, are refused past two upstream calls
+	cfg := weather.Config{Units: weather.Metric, APIKey: "key", CacheExpirationDur: time.Minute,
+		UpstreamCallsPerMonth: 4, UpstreamQuotaReserve: 0.5, Locations: map[string]weather.Location{"office": {City: "Bogota", Country: "CO"}}}
+	weatherHandler := NewWeatherHandler(&cfg, &mockClient)
+	exporter := NewExporterHandler(weatherHandler)
+
+	scrape := func() string {
+		req, err := http.NewRequest("GET", "/metrics/weather", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		exporter.ServeHTTP(rr, req)
+		assert.Equal(t, 200, rr.Code)
+		return rr.Body.String()
+	}
+
+	values := []string{
+		`weather_temperature{location="office",units="metric"} 14.5`,
+		`weather_humidity_percent{location="office"} 72`,
+		`weather_pressure_hpa{location="office"} 1027`,
+		`weather_wind_speed{location="office",units="metric"} 2.1`,
+		`weather_precipitation_probability{location="office"} 0.4`,
+		`weather_observation_timestamp_seconds{location="office"} 1.6088436e+09`,
+	}
+
+	// Scrapes never call the upstream, the location is not published until the prefetcher caches its weather
+	assert.NotContains(t, scrape(), "weather_temperature")
+	assert.Equal(t, 0, calls)
+
+	prefetch := weather.WithPriority(context.Background(), weather.NonEssential)
+	weatherHandler.prefetch.refresh(prefetch)
+	assert.Equal(t, 2, calls)
+
+	body := scrape()
+	for _, line := range append(values, `weather_exporter_up{location="office"} 1`) {
+		assert.Contains(t, body, line+"\n")
+	}
+
+	// The next scrape is answered from the cached data
+	assert.Equal(t, body, scrape())
+	assert.Equal(t, 2, calls)
+
+	// Once the data expires the quota refuses the prefetcher's calls, the last known values are kept
+	weatherHandler.FlushCache()
+	weatherHandler.prefetch.refresh(prefetch)
+	body = scrape()
+	for _, line := range append(values, `weather_exporter_up{location="office"} 0`) {
+		assert.Contains(t, body, line+"\n")
+	}
+	assert.Equal(t, 2, calls)
+
+	// Locations removed from the config are no longer published
+	reloaded := cfg
+	reloaded.Locations = nil
+	weatherHandler.Reload(&reloaded)
+	assert.NotContains(t, scrape(), `location="office"`)
+}
@@ -0,0 +1,158 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/mpfrancis/weather"
+)
+
+// healthProbeKey is the key of the entry written to the response cache to check that it is usable.
+const healthProbeKey = "health-probe"
+
+var (
+	errCacheUnusable  = errors.New("Response cache is not usable")
+	errConfigRejected = errors.New("The latest configuration is invalid, the previous one is still in use")
+)
+
+// Check is the outcome of a readiness check.
+type Check struct {
+	Name  string `json:"name"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// Health is the health of a weather handler: the outcome of its readiness checks, the health of its providers
+// and the upstream usage. It is ready when every check passed.
+type Health struct {
+	Ready  bool          `json:"ready"`
+	Checks []Check       `json:"checks"`
+	Usage  weather.Usage `json:"usage"`
+	weather.Health
+}
+
+// Health checks that the handler can serve requests: its latest config was loaded, its response cache is usable
+// and a provider is available within the upstream quota. Providers are not called, their availability is that of
+// their circuit breakers and API keys.
+func (h *WeatherHandler) Health() Health {
+	health := Health{Ready: true, Usage: h.Usage(), Health: h.service.Health()}
+
+	check := func(name string, err error) {
+		c := Check{Name: name, OK: err == nil}
+		if err != nil {
+			c.Error = err.Error()
+			health.Ready = false
+		}
+		health.Checks = append(health.Checks, c)
+	}
+
+	check("config", h.checkConfig())
+	check("cache", h.checkCache())
+
+	var err error
+	if !health.Available() {
+		err = weather.ErrNoProvider
+	}
+	check("upstream", err)
+
+	err = nil
+	if health.Usage.Exhausted() {
+		err = weather.ErrQuotaExceeded
+	}
+	check("quota", err)
+
+	return health
+}
+
+// checkConfig reports why the latest config was rejected, if it was.
+func (h *WeatherHandler) checkConfig() error {
+	h.reloadMu.Lock()
+	defer h.reloadMu.Unlock()
+
+	if h.reloadErr != nil {
+		return fmt.Errorf("%w, %s", errConfigRejected, h.reloadErr)
+	}
+
+	return nil
+}
+
+// checkCache writes an entry to the response cache and reads it back.
+func (h *WeatherHandler) checkCache() error {
+	now := time.Now()
+	h.responseCache.Set(healthProbeKey, now, time.Minute)
+	defer h.responseCache.Delete(healthProbeKey)
+
+	if v, ok := h.responseCache.Get(healthProbeKey); !ok || v != now {
+		return errCacheUnusable
+	}
+
+	return nil
+}
+
+// LiveHandler is the handler for the /livez endpoint, it responds as long as the process serves requests.
+type LiveHandler struct{}
+
+// ServeHTTP handles a liveness request.
+func (LiveHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	fmt.Fprintln(w, "ok")
+}
+
+// ReadyHandler is the handler for the /readyz endpoint. It responds with 503 Service Unavailable and the failed checks
+// when the weather handler is not ready to serve requests.
+type ReadyHandler struct {
+	weather *WeatherHandler
+}
+
+// NewReadyHandler returns a new instance of the readiness http handler.
+func NewReadyHandler(weather *WeatherHandler) *ReadyHandler {
+	return &ReadyHandler{weather: weather}
+}
+
+// ServeHTTP handles a readiness request.
+func (h *ReadyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+
+	health := h.weather.Health()
+	if health.Ready {
+		fmt.Fprintln(w, "ok")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	for _, c := range health.Checks {
+		if !c.OK {
+			fmt.Fprintf(w, "%s: %s\n", c.Name, c.Error)
+		}
+	}
+}
+
+// HealthHandler is the handler for the /healthz endpoint, reporting the health of the weather handler in detail.
+// It responds with 503 Service Unavailable when the weather handler is not ready.
+type HealthHandler struct {
+	weather *WeatherHandler
+}
+
+// NewHealthHandler returns a new instance of the health http handler.
+func NewHealthHandler(weather *WeatherHandler) *HealthHandler {
+	return &HealthHandler{weather: weather}
+}
+
+// ServeHTTP handles a health request.
+func (h *HealthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+
+	health := h.weather.Health()
+	if !health.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	if err := json.NewEncoder(w).Encode(health); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,95 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mpfrancis/weather"
+	"github.com/mpfrancis/weather/internal/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthHandlers(t *testing.T) {
+	cfg := weather.Config{Units: weather.Metric, APIKey: "revoked", APIKeyCooldown: time.Hour, UpstreamCallsPerMonth: 2}
+	var mockClient mock.Client
+	mockClient.GetFn = func(url string) (resp *http.Response, err error) {
+		if strings.Contains(url, "appid=revoked") {
+			return &http.Response{StatusCode: 401, Body: ioutil.NopCloser(strings.NewReader(`{"cod": 401}`))}, nil
+		}
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(`{"name": "Cali", "sys": {"country": "CO"}}`))}, nil
+	}
+	weatherHandler := NewWeatherHandler(&cfg, &mockClient)
+
+	serve := func(h http.Handler, url string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := serve(NewReadyHandler(weatherHandler), "/readyz")
+	assert.Equal(t, 200, rr.Code)
+	assert.Equal(t, "ok\n", rr.Body.String())
+
+	// The only API key is rejected by the upstream, the instance is alive but not ready
+	assert.Equal(t, 503, serve(weatherHandler, "/weather?city=Bogota&country=co").Code)
+	assert.Equal(t, 200, serve(LiveHandler{}, "/livez").Code)
+
+	rr = serve(NewReadyHandler(weatherHandler), "/readyz")
+	assert.Equal(t, 503, rr.Code)
+	assert.Equal(t, "upstream: "+weather.ErrNoProvider.Error()+"\n", rr.Body.String())
+
+	rr = serve(NewHealthHandler(weatherHandler), "/healthz")
+	assert.Equal(t, 503, rr.Code)
+	assert.Equal(t, "application/json; charset=utf-8", rr.Header().Get("Content-Type"))
+	var health Health
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &health))
+	assert.False(t, health.Ready)
+	assert.Equal(t, []Check{
+		{Name: "config", OK: true},
+		{Name: "cache", OK: true},
+		{Name: "upstream", Error: weather.ErrNoProvider.Error()},
+		{Name: "quota", OK: true},
+	}, health.Checks)
+	assert.Equal(t, 1, health.Usage.Month)
+	assert.Equal(t, 1, health.APIKeys)
+	assert.Equal(t, 0, health.APIKeysInService)
+	assert.Len(t, health.Providers, 1)
+	assert.Equal(t, weather.OpenWeatherProvider, health.Providers[0].Provider)
+	assert.False(t, health.Providers[0].Available)
+	assert.Equal(t, weather.BreakerClosed, health.Providers[0].State)
+
+	// A valid key brings the instance back, until the upstream quota is exhausted
+	weatherHandler.SetAPIKeys([]weather.APIKey{{Key: "valid", Weight: 1}})
+	assert.Equal(t, 200, serve(NewReadyHandler(weatherHandler), "/readyz").Code)
+	assert.Equal(t, 200, serve(weatherHandler, "/weather?city=Cali&country=co").Code)
+
+	rr = serve(NewReadyHandler(weatherHandler), "/readyz")
+	assert.Equal(t, 503, rr.Code)
+	assert.Equal(t, "quota: "+weather.ErrQuotaExceeded.Error()+"\n", rr.Body.String())
+}
+
+func TestHealthConfigRejected(t *testing.T) {
+	cfg := weather.Config{Units: weather.Metric, APIKey: "key"}
+	weatherHandler := NewWeatherHandler(&cfg, &mock.Client{})
+	assert.True(t, weatherHandler.Health().Ready)
+
+	// The instance is not ready while the latest config is rejected, until a valid one is reloaded
+	weatherHandler.RejectReload(errors.New("Invalid log level"))
+	health := weatherHandler.Health()
+	assert.False(t, health.Ready)
+	assert.Equal(t, Check{Name: "config", Error: errConfigRejected.Error() + ", Invalid log level"}, health.Checks[0])
+
+	weatherHandler.Reload(&cfg)
+	assert.True(t, weatherHandler.Health().Ready)
+}
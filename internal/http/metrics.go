@@ -0,0 +1,185 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// panics counts the panics recovered by the handlers of every server.
+var panics uint64
+
+// serverMetrics are the metrics of a server, served by /metrics.
+type serverMetrics struct {
+	registry         *prometheus.Registry
+	requests         *prometheus.CounterVec
+	latency          *prometheus.HistogramVec
+	upstreamRequests *prometheus.CounterVec
+	upstreamErrors   *prometheus.CounterVec
+	upstreamLatency  *prometheus.HistogramVec
+}
+
+// newServerMetrics registers the metrics of requests, upstream calls and panics.
+func newServerMetrics() *serverMetrics {
+	m := &serverMetrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "weather_http_requests_total",
+			Help: "Requests served, by route and status code.",
+		}, []string{"route", "status"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "weather_http_request_duration_seconds",
+			Help: "Time taken to serve requests, by route and status code.",
+		}, []string{"route", "status"}),
+		upstreamRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "weather_upstream_requests_total",
+			Help: "Upstream calls, by endpoint and status code, error when no response was received.",
+		}, []string{"endpoint", "status"}),
+		upstreamErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "weather_upstream_errors_total",
+			Help: "Upstream calls that failed or got an error status code, by endpoint.",
+		}, []string{"endpoint"}),
+		upstreamLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "weather_upstream_request_duration_seconds",
+			Help: "Time taken by upstream calls, by endpoint.",
+		}, []string{"endpoint"}),
+	}
+
+	m.registry.MustRegister(m.requests, m.latency, m.upstreamRequests, m.upstreamErrors, m.upstreamLatency,
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "weather_http_panics_total",
+			Help: "Panics recovered while serving requests.",
+		}, func() float64 {
+			return float64(atomic.LoadUint64(&panics))
+		}),
+	)
+
+	return m
+}
+
+// handler serves the metrics in the Prometheus text format.
+func (m *serverMetrics) handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// observe registers the metrics of the response cache and the upstream quota of the weather handler.
+func (m *serverMetrics) observe(h *WeatherHandler) {
+	m.registry.MustRegister(handlerCollector{h: h})
+}
+
+var (
+	cacheHitsDesc      = prometheus.NewDesc("weather_response_cache_hits_total", "Requests answered from the response cache.", nil, nil)
+	cacheMissesDesc    = prometheus.NewDesc("weather_response_cache_misses_total", "Requests not found in the response cache.", nil, nil)
+	cacheEvictionsDesc = prometheus.NewDesc("weather_response_cache_evictions_total", "Responses removed from the response cache once expired.", nil, nil)
+	cacheEntriesDesc   = prometheus.NewDesc("weather_response_cache_entries", "Responses in the response cache.", nil, nil)
+	quotaCallsDesc     = prometheus.NewDesc("weather_upstream_quota_calls", "Upstream calls made within the rolling minute and the calendar month.",
+		[]string{"window"}, nil)
+	quotaLimitDesc = prometheus.NewDesc("weather_upstream_quota_limit", "Upstream calls allowed per minute and per month, zero means unlimited.",
+		[]string{"window"}, nil)
+)
+
+// handlerCollector collects the response cache statistics and the upstream usage of the weather handler.
+// Both are read once per collection, the cache statistics walk the service's caches and their values must agree.
+type handlerCollector struct {
+	h *WeatherHandler
+}
+
+// Describe sends the descriptions of the handler's metrics.
+func (c handlerCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{cacheHitsDesc, cacheMissesDesc, cacheEvictionsDesc, cacheEntriesDesc, quotaCallsDesc, quotaLimitDesc} {
+		ch <- d
+	}
+}
+
+// Collect sends the current cache statistics, usage and limits.
+func (c handlerCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.h.CacheStats()
+	ch <- prometheus.MustNewConstMetric(cacheHitsDesc, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(cacheMissesDesc, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(cacheEvictionsDesc, prometheus.CounterValue, float64(stats.Evictions))
+	ch <- prometheus.MustNewConstMetric(cacheEntriesDesc, prometheus.GaugeValue, float64(stats.Responses))
+
+	usage := c.h.Usage()
+	ch <- prometheus.MustNewConstMetric(quotaCallsDesc, prometheus.GaugeValue, float64(usage.Minute), "minute")
+	ch <- prometheus.MustNewConstMetric(quotaCallsDesc, prometheus.GaugeValue, float64(usage.Month), "month")
+	ch <- prometheus.MustNewConstMetric(quotaLimitDesc, prometheus.GaugeValue, float64(usage.MinuteLimit), "minute")
+	ch <- prometheus.MustNewConstMetric(quotaLimitDesc, prometheus.GaugeValue, float64(usage.MonthLimit), "month")
+}
+
+// instrument counts the requests served by the handler and their latency, labeled with the route.
+func (m *serverMetrics) instrument(route string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		status := strconv.Itoa(rec.Status())
+		m.requests.WithLabelValues(route, status).Inc()
+		m.latency.WithLabelValues(route, status).Observe(time.Since(start).Seconds())
+	})
+}
+
+// client returns a client making the calls of the given client, counting them along with their latency and errors.
+func (m *serverMetrics) client(client Clienter) Clienter {
+	return &instrumentedClient{client: client, metrics: m}
+}
+
+// instrumentedClient counts the upstream calls made with a client, labeled with their endpoint: the host and path of the URL.
+type instrumentedClient struct {
+	client  Clienter
+	metrics *serverMetrics
+}
+
+// Do makes the call with the underlying client.
+func (c *instrumentedClient) Do(req *http.Request) (*http.Response, error) {
+	endpoint := req.URL.Host + req.URL.Path
+	start := time.Now()
+	resp, err := c.client.Do(req)
+	c.metrics.upstreamLatency.WithLabelValues(endpoint).Observe(time.Since(start).Seconds())
+
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+	c.metrics.upstreamRequests.WithLabelValues(endpoint, status).Inc()
+
+	if err != nil || resp.StatusCode >= http.StatusBadRequest {
+		c.metrics.upstreamErrors.WithLabelValues(endpoint).Inc()
+	}
+
+	return resp, err
+}
+
+// statusRecorder records the status code written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader records the status code and writes it.
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Write writes the body, the status code is 200 unless one was written.
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// Status returns the status code written, 200 when the handler wrote nothing.
+func (r *statusRecorder) Status() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
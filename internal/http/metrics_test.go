@@ -0,0 +1,71 @@
+package http
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mpfrancis/weather"
+	"github.com/mpfrancis/weather/internal/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerMetrics(t *testing.T) {
+	cfg := weather.Config{BaseURL: "http://upstream/data/2.5", APIKey: "key", Units: weather.Metric, UpstreamCallsPerMinute: 60}
+	var mockClient mock.Client
+	mockClient.GetFn = func(url string) (resp *http.Response, err error) {
+		body := `{"coord": {"lon": -74.08, "lat": 4.61}, "name": "Bogotá", "sys": {"country": "CO"}}`
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(body))}, nil
+	}
+	s := NewServer(&cfg, &mockClient)
+	server := httptest.NewServer(s.Handler)
+	defer server.Close()
+
+	get := func(path string) string {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(body)
+	}
+
+	// The second request is answered from the response cache
+	get("/weather?city=Bogota&country=co")
+	get("/weather?city=Bogota&country=co")
+	get("/weather?city=Bogota")
+
+	resp, err := http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", resp.Header.Get("Content-Type"))
+
+	body := get("/metrics")
+	for _, line := range []string{
+		`weather_http_requests_total{route="/weather",status="200"} 2`,
+		`weather_http_requests_total{route="/weather",status="422"} 1`,
+		`weather_http_requests_total{route="/metrics",status="200"} 1`,
+		`weather_http_request_duration_seconds_count{route="/weather",status="200"} 2`,
+		`weather_upstream_requests_total{endpoint="upstream/data/2.5/weather",status="200"} 1`,
+		`weather_upstream_request_duration_seconds_count{endpoint="upstream/data/2.5/weather"} 1`,
+		`weather_response_cache_hits_total 1`,
+		`weather_response_cache_misses_total 1`,
+		`weather_response_cache_evictions_total 0`,
+		`weather_response_cache_entries 1`,
+		`weather_upstream_quota_calls{window="minute"} 1`,
+		`weather_upstream_quota_limit{window="minute"} 60`,
+		"# TYPE weather_http_panics_total counter",
+	} {
+		assert.Contains(t, body, line+"\n")
+	}
+	assert.NotContains(t, body, "weather_upstream_errors_total{")
+}
@@ -0,0 +1,203 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mpfrancis/weather"
+	"github.com/sirupsen/logrus"
+)
+
+// prefetcher tracks how often each request is made and refreshes the responses to the most frequent ones
+// shortly before they expire from the response cache, so popular locations are almost always served warm.
+// Request counts decay every cycle so that the ranking follows recent traffic.
+// The weather at the configured locations is refreshed first, it is what the exporter publishes.
+type prefetcher struct {
+	handler *WeatherHandler
+
+	mu     sync.Mutex
+	counts map[string]*requestCount
+	calls  []time.Time // Upstream calls made by the prefetcher within the last minute.
+}
+
+// requestCount is the decaying number of times a request has been made.
+type requestCount struct {
+	req  weather.Query
+	hits float64
+}
+
+func newPrefetcher(h *WeatherHandler) *prefetcher {
+	return &prefetcher{
+		handler: h,
+		counts:  make(map[string]*requestCount),
+	}
+}
+
+// record counts a request.
+func (p *prefetcher) record(req weather.Query) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	c, ok := p.counts[req.Key()]
+	if !ok {
+		c = &requestCount{req: req}
+		p.counts[req.Key()] = c
+	}
+	c.hits++
+}
+
+// run refreshes the cache every half lead time until the context is done.
+func (p *prefetcher) run(ctx context.Context) {
+	interval := p.handler.config().PrefetchLead / 2
+	if interval < time.Second {
+		interval = time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.refresh(ctx)
+		}
+	}
+}
+
+// refresh fetches the configured locations and the top requests whose responses are missing or expire within the lead time.
+// Refreshes stop once the prefetch share of the upstream budget for the current minute is used up,
+// or once the upstream quota sheds non-essential calls. Every upstream call of a refresh is charged against the budget.
+func (p *prefetcher) refresh(ctx context.Context) {
+	h := p.handler
+	for _, req := range append(p.exported(), p.top(h.config().PrefetchTopN)...) {
+		if _, expiration, ok := h.responseCache.GetWithExpiration(req.Key()); ok {
+			if expiration.IsZero() || time.Until(expiration) > h.config().PrefetchLead {
+				continue
+			}
+		}
+
+		req.Refresh = true
+		report, err := h.service.Lookup(context.WithValue(ctx, prefetchKey{}, true), req)
+		if errors.Is(err, weather.ErrQuotaExceeded) {
+			return
+		} else if err != nil {
+			logrus.WithField("location", req.Location.String()).Warnf("Unable to prefetch weather: %s", err)
+			continue
+		}
+
+		if _, err := h.store(req, report); err != nil {
+			logrus.WithField("location", req.Location.String()).Warnf("Unable to prefetch weather: %s", err)
+		}
+	}
+}
+
+// exported returns the queries of today's forecast at the configured locations, which also fetch their current conditions.
+// They use the configured units and the default language, as the exporter reads them.
+func (p *prefetcher) exported() []weather.Query {
+	cfg := p.handler.config()
+	reqs := make([]weather.Query, 0, len(cfg.Locations))
+	for _, loc := range cfg.Locations {
+		reqs = append(reqs, weather.Query{
+			Location: loc.Normalize(),
+			Units:    cfg.Units,
+			Lang:     weather.DefaultLang,
+			Forecast: 0,
+		})
+	}
+
+	sort.Slice(reqs, func(i, j int) bool { return reqs[i].Key() < reqs[j].Key() })
+
+	return reqs
+}
+
+// top returns the n most frequent requests and decays all request counts.
+func (p *prefetcher) top(n int) []weather.Query {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	counts := make([]*requestCount, 0, len(p.counts))
+	for key, c := range p.counts {
+		counts = append(counts, c)
+
+		c.hits /= 2
+		if c.hits < 0.1 {
+			delete(p.counts, key)
+		}
+	}
+
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].hits != counts[j].hits {
+			return counts[i].hits > counts[j].hits
+		}
+		return counts[i].req.Key() < counts[j].req.Key()
+	})
+
+	if len(counts) > n {
+		counts = counts[:n]
+	}
+
+	reqs := make([]weather.Query, len(counts))
+	for i := range counts {
+		reqs[i] = counts[i].req
+	}
+
+	return reqs
+}
+
+// reserve records an upstream call if it fits within the prefetch budget for the last minute.
+// The budget is the prefetch share of the calls allowed per minute, rounded up so that small limits still allow a call.
+// There is no budget when the calls per minute are unlimited, the upstream quota still defers prefetching near the monthly limit.
+func (p *prefetcher) reserve() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	for len(p.calls) > 0 && now.Sub(p.calls[0]) >= time.Minute {
+		p.calls = p.calls[1:]
+	}
+
+	cfg := p.handler.config()
+	if cfg.UpstreamCallsPerMinute > 0 {
+		// The epsilon keeps products such as 0.1*30 = 3.0000000000000004 from rounding up to the next call
+		budget := int(math.Ceil(cfg.PrefetchShare*float64(cfg.UpstreamCallsPerMinute) - 1e-9))
+		if len(p.calls) >= budget {
+			return false
+		}
+	}
+
+	p.calls = append(p.calls, now)
+
+	return true
+}
+
+// prefetchKey marks the contexts of the lookups made by the prefetcher.
+type prefetchKey struct{}
+
+// client returns a client making the calls of the given client. Calls made for the prefetcher are charged against its
+// budget one by one, so that failovers, ensemble forecasts and coordinate lookups cost the calls they make, and are
+// refused with ErrQuotaExceeded once the budget is used up.
+func (p *prefetcher) client(client Clienter) Clienter {
+	return budgetedClient{client: client, prefetch: p}
+}
+
+// budgetedClient charges the calls made for the prefetcher against its budget.
+type budgetedClient struct {
+	client   Clienter
+	prefetch *prefetcher
+}
+
+// Do makes the call with the underlying client, unless it is made for the prefetcher and does not fit within its budget.
+func (c budgetedClient) Do(req *http.Request) (*http.Response, error) {
+	if req.Context().Value(prefetchKey{}) != nil && !c.prefetch.reserve() {
+		return nil, weather.ErrQuotaExceeded
+	}
+
+	return c.client.Do(req)
+}
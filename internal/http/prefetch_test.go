@@ -0,0 +1,157 @@
+package http
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mpfrancis/weather"
+	"github.com/mpfrancis/weather/internal/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrefetch(t *testing.T) {
+	cfg := weather.Config{
+		Units:                  weather.Metric,
+		CacheExpirationDur:     10 * time.Minute,
+		UpstreamCallsPerMinute: 10,
+		PrefetchTopN:           2,
+		PrefetchLead:           time.Minute,
+		PrefetchShare:          0.2,
+	}
+	mockClient := mock.Client{}
+	handler := NewWeatherHandler(&cfg, &mockClient)
+
+	var mu sync.Mutex
+	var fetched []string
+	mockClient.GetFn = func(url string) (resp *http.Response, err error) {
+		mu.Lock()
+		fetched = append(fetched, url)
+		mu.Unlock()
+
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(`{"name": "Bogotá", "sys": {"country": "CO"}}`))}, nil
+	}
+
+	request := func(city string) weather.Query {
+		return weather.Query{Location: weather.Location{City: city, Country: "CO"}, Units: weather.Metric, Lang: weather.DefaultLang, Forecast: -1}
+	}
+	bogota, medellin, cali, pasto := request("bogota"), request("medellin"), request("cali"), request("pasto")
+
+	for i := 0; i < 5; i++ {
+		handler.prefetch.record(bogota)
+	}
+	for i := 0; i < 3; i++ {
+		handler.prefetch.record(medellin)
+		handler.prefetch.record(cali)
+	}
+	handler.prefetch.record(pasto)
+
+	// Bogota is about to expire, Cali is fresh and Medellin is not cached at all
+	handler.responseCache.Set(bogota.Key(), &cachedResponse{}, 30*time.Second)
+	handler.responseCache.Set(cali.Key(), &cachedResponse{}, 10*time.Minute)
+
+	handler.prefetch.refresh(weather.WithPriority(context.Background(), weather.NonEssential))
+
+	// Only the two most requested locations are considered, and only Bogota needs a refresh
+	assert.Len(t, fetched, 1)
+	assert.Contains(t, fetched[0], "q=bogota%2CCO")
+	_, expiration, ok := handler.responseCache.GetWithExpiration(bogota.Key())
+	assert.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(10*time.Minute), expiration, 5*time.Second)
+
+	// Medellin and Pasto become the most requested and neither is cached,
+	// but the prefetch budget of two calls per minute allows only one more fetch
+	for i := 0; i < 10; i++ {
+		handler.prefetch.record(medellin)
+		handler.prefetch.record(pasto)
+	}
+	handler.responseCache.Delete(bogota.Key())
+
+	handler.prefetch.refresh(weather.WithPriority(context.Background(), weather.NonEssential))
+
+	assert.Len(t, fetched, 2)
+	assert.Contains(t, fetched[1], "q=medellin%2CCO")
+	_, ok = handler.responseCache.Get(medellin.Key())
+	assert.True(t, ok)
+}
+
+func TestPrefetchBudget(t *testing.T) {
+	cases := []struct {
+		name           string
+		callsPerMinute int
+		share          float64
+		expected       int
+	}{
+		{"Share of the limit", 10, 0.2, 2},
+		{"Rounded up", 4, 0.2, 1},
+		{"Exact product", 30, 0.1, 3},
+		{"No share", 10, 0, 0},
+		{"Unlimited", 0, 0.2, 100},
+	}
+
+	for _, c := range cases {
+		cfg := weather.Config{Units: weather.Metric, UpstreamCallsPerMinute: c.callsPerMinute, PrefetchTopN: 1, PrefetchShare: c.share}
+		p := NewWeatherHandler(&cfg, &mock.Client{}).prefetch
+
+		reserved := 0
+		for i := 0; i < 100 && p.reserve(); i++ {
+			reserved++
+		}
+		assert.Equal(t, c.expected, reserved, c.name)
+	}
+}
+
+func TestPrefetchCost(t *testing.T) {
+	cfg := weather.Config{
+		Units:                  weather.Metric,
+		BaseURL:                "http://openweather",
+		OpenMeteoURL:           "http://openmeteo",
+		OpenMeteoGeocodingURL:  "http://geocoding",
+		Providers:              []string{weather.OpenWeatherProvider, weather.OpenMeteoProvider},
+		CacheExpirationDur:     10 * time.Minute,
+		UpstreamCallsPerMinute: 10,
+		PrefetchTopN:           2,
+		PrefetchShare:          0.5,
+	}
+	mockClient := mock.Client{}
+	handler := NewWeatherHandler(&cfg, &mockClient)
+
+	var mu sync.Mutex
+	calls := 0
+	mockClient.GetFn = func(url string) (resp *http.Response, err error) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+
+		switch {
+		case strings.HasPrefix(url, "http://openweather"):
+			return &http.Response{StatusCode: 500, Body: ioutil.NopCloser(strings.NewReader(`{"cod": 500}`))}, nil
+		case strings.HasPrefix(url, "http://geocoding"):
+			return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(`{"results": [{"name": "Bogotá", "latitude": 4.61, "longitude": -74.08, "country_code": "CO"}]}`))}, nil
+		default:
+			return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(`{"latitude": 4.61, "longitude": -74.08, "current": {"time": 1605182400, "temperature_2m": 14}}`))}, nil
+		}
+	}
+
+	request := func(city string) weather.Query {
+		return weather.Query{Location: weather.Location{City: city, Country: "CO"}, Units: weather.Metric, Lang: weather.DefaultLang, Forecast: -1}
+	}
+	bogota, cali := request("bogota"), request("cali")
+	handler.prefetch.record(bogota)
+	handler.prefetch.record(bogota)
+	handler.prefetch.record(cali)
+
+	handler.prefetch.refresh(weather.WithPriority(context.Background(), weather.NonEssential))
+
+	// Failing over costs three calls, the budget of five calls is used up before Cali is served by the second provider
+	assert.Equal(t, 5, calls)
+	_, ok := handler.responseCache.Get(bogota.Key())
+	assert.True(t, ok)
+	_, ok = handler.responseCache.Get(cali.Key())
+	assert.False(t, ok)
+	assert.False(t, handler.prefetch.reserve())
+}
@@ -0,0 +1,140 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/mpfrancis/weather"
+)
+
+var (
+	errMissingCity     = errors.New("Query parameter 'city' is required")
+	errMissingCountry  = errors.New("Query parameter 'country' is required")
+	errInvalidCountry  = errors.New("Query parameter 'country' is invalid, please provide a two letter ISO 3166 country code")
+	errUnknownLocation = errors.New("Query parameter 'location' is not a configured location")
+	errInvalidCoord    = errors.New("Query parameters 'lat' and 'lon' are invalid, please provide a latitude between -90 and 90 and a longitude between -180 and 180")
+	errInvalidForecast = errors.New("Query parameter 'forecast' is invalid, please provide a number between 0 and 6")
+	errInvalidUnits    = errors.New("Query parameter 'units' is invalid, use: standard, metric, imperial")
+	errInvalidLang     = errors.New("Query parameter 'lang' is invalid, please provide a language code such as 'en' or 'pt_br'")
+	errInvalidEnsemble = errors.New("Query parameter 'ensemble' is invalid, use: true, false")
+	errEnsembleDay     = errors.New("Query parameter 'ensemble' requires the 'forecast' parameter")
+)
+
+// parseWeatherRequest reads the query parameters of a /weather request into a query of the weather service.
+func parseWeatherRequest(r *http.Request, cfg *weather.Config) (weather.Query, error) {
+	if err := r.ParseForm(); err != nil {
+		return weather.Query{}, err
+	}
+
+	return ParseQuery(r.Form, cfg)
+}
+
+// ParseQuery reads the parameters of a /weather request into a query of the weather service.
+// The location is given by the city, zip or lat and lon parameters, or named by the location parameter.
+// Optional parameters are resolved to their defaults, so that equivalent requests produce equal values.
+func ParseQuery(values url.Values, cfg *weather.Config) (weather.Query, error) {
+	req := weather.Query{
+		Location: weather.Location{City: values.Get("city"), Country: values.Get("country"), Zip: values.Get("zip")}.Normalize(),
+		Units:    cfg.Units,
+		Lang:     weather.DefaultLang,
+		Forecast: -1,
+	}
+
+	if name := values.Get("location"); name != "" {
+		loc, ok := cfg.Locations[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return req, errUnknownLocation
+		}
+		req.Location = loc
+	} else if err := parseLocation(values, &req.Location); err != nil {
+		return req, err
+	}
+
+	if forecast := values.Get("forecast"); forecast != "" {
+		day, err := strconv.Atoi(forecast)
+		if err != nil || day < 0 || 6 < day {
+			return req, errInvalidForecast
+		}
+		req.Forecast = day
+	}
+
+	if units := strings.ToLower(strings.TrimSpace(values.Get("units"))); units != "" {
+		req.Units = weather.Unit(units)
+		if !req.Units.Valid() {
+			return req, errInvalidUnits
+		}
+	}
+
+	if lang := strings.ToLower(strings.TrimSpace(values.Get("lang"))); lang != "" {
+		if !validLang(lang) {
+			return req, errInvalidLang
+		}
+		req.Lang = lang
+	}
+
+	if ensemble := values.Get("ensemble"); ensemble != "" {
+		var err error
+		if req.Ensemble, err = strconv.ParseBool(ensemble); err != nil {
+			return req, errInvalidEnsemble
+		}
+
+		if req.Ensemble && req.Forecast < 0 {
+			return req, errEnsembleDay
+		}
+	}
+
+	return req, nil
+}
+
+// parseLocation checks the location of a request, given by its coordinates, its zip code and country or its city and country.
+// Coordinates take precedence over the zip code, which takes precedence over the city.
+func parseLocation(values url.Values, loc *weather.Location) error {
+	if lat, lon := values.Get("lat"), values.Get("lon"); lat != "" || lon != "" {
+		var coord weather.Coord
+		var err error
+		if coord.Lat, err = strconv.ParseFloat(lat, 64); err != nil {
+			return errInvalidCoord
+		}
+
+		if coord.Lon, err = strconv.ParseFloat(lon, 64); err != nil || coord.Validate() != nil {
+			return errInvalidCoord
+		}
+
+		*loc = weather.Location{Coord: &coord}
+		return nil
+	}
+
+	if loc.Zip != "" {
+		loc.City = ""
+	} else if loc.City == "" {
+		return errMissingCity
+	}
+
+	if loc.Country == "" {
+		return errMissingCountry
+	}
+
+	if !loc.ValidCountry() {
+		return errInvalidCountry
+	}
+
+	return nil
+}
+
+// validLang reports whether lang looks like an open weather language code, e.g. "en", "zh_cn".
+func validLang(lang string) bool {
+	if len(lang) < 2 || 5 < len(lang) {
+		return false
+	}
+
+	for _, c := range lang {
+		if (c < 'a' || 'z' < c) && c != '_' {
+			return false
+		}
+	}
+
+	return true
+}
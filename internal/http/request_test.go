@@ -0,0 +1,54 @@
+package http
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/mpfrancis/weather"
+	"github.com/stretchr/testify/assert"
+)
+
+type queryKeyCase struct {
+	url         string
+	expectedKey string
+}
+
+func TestWeatherRequestKey(t *testing.T) {
+	cfg := weather.Config{Units: weather.Metric, Locations: map[string]weather.Location{"home": {City: "bogota", Country: "CO"}}}
+	cases := []queryKeyCase{
+		{"/weather?city=Bogota&country=co", "bogota|CO|metric|en|-1"},
+		{"/weather?country=CO&city=bogota", "bogota|CO|metric|en|-1"},
+		{"/weather?city=Bogota&country=co&utm=x", "bogota|CO|metric|en|-1"},
+		{"/weather?city=New%20%20York&country=us&units=Imperial&lang=pt_BR&forecast=2", "new york|US|imperial|pt_br|2"},
+		{"/weather?city=Bogota&country=co&forecast=1&ensemble=true", "bogota|CO|metric|en|1|ensemble"},
+		{"/weather?city=Bogota&country=co&forecast=1&ensemble=0", "bogota|CO|metric|en|1"},
+		{"/weather?zip=sw1a%201aa&country=gb&city=London", "zip:SW1A 1AA|GB|metric|en|-1"},
+		{"/weather?lat=4.61&lon=-74.08", "@4.61,-74.08|metric|en|-1"},
+		{"/weather?lat=4.61&lon=-74.08&city=Bogota&country=co", "@4.61,-74.08|metric|en|-1"},
+		{"/weather?location=Home&lat=4.61&lon=-74.08", "bogota|CO|metric|en|-1"},
+	}
+
+	for i := range cases {
+		r, err := http.NewRequest("GET", cases[i].url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req, err := parseWeatherRequest(r, &cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, cases[i].expectedKey, req.Key(), cases[i].url)
+	}
+}
+
+func TestWeatherRequestUnknownLocation(t *testing.T) {
+	r, err := http.NewRequest("GET", "/weather?location=office", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = parseWeatherRequest(r, &weather.Config{Units: weather.Metric})
+	assert.Equal(t, errUnknownLocation, err)
+}
@@ -0,0 +1,20 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/mpfrancis/weather"
+)
+
+// schemaPath is the path at which the JSON schema of the current /weather response is published.
+const schemaPath = "/schema/" + weather.SchemaVersion
+
+// SchemaHandler is the handler for the /schema/v1 endpoint, publishing the JSON schema of the canonical model.
+type SchemaHandler struct{}
+
+// ServeHTTP handles a schema request.
+func (SchemaHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/schema+json")
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Write([]byte(weather.SchemaV1))
+}
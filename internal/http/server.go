@@ -1,34 +1,131 @@
 package http
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/mpfrancis/weather"
-	"github.com/sirupsen/logrus"
+)
+
+const (
+	// readHeaderTimeout bounds reading the request headers, so slow clients cannot hold connections open.
+	readHeaderTimeout = 5 * time.Second
+	// idleTimeout is how long an idle keep-alive connection is kept open.
+	idleTimeout = 2 * time.Minute
+	// minWriteTimeout is the least time allowed to handle a request and write its response.
+	minWriteTimeout = 30 * time.Second
 )
 
 // Server is the weather API's server object.
 type Server struct {
 	*http.Server
+	weather    *WeatherHandler
+	cancel     context.CancelFunc
+	background sync.WaitGroup
 }
 
 // NewServer creates a new instance of the server object for serving up the API.
+// Background work such as prefetching and saving upstream usage runs until the server is shut down.
 func NewServer(cfg *weather.Config, client Clienter) *Server {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	m := newServerMetrics()
+	weatherHandler := NewWeatherHandler(cfg, m.client(loggingClient{client: client}))
+	m.observe(weatherHandler)
+	s := &Server{weather: weatherHandler, cancel: cancel}
+	s.run(func() { weatherHandler.Prefetch(ctx) })
+	s.run(func() { weatherHandler.quota.Persist(ctx, time.Minute) })
+
+	// Every route is counted in the metrics, by its pattern
 	mux := http.NewServeMux()
-	mux.Handle("/weather", recovery(NewWeatherHandler(cfg, client)))
-	mux.HandleFunc("/healthcheck", func(w http.ResponseWriter, r *http.Request) {})
-	return &Server{&http.Server{Addr: cfg.ServerAddress, Handler: mux}}
+	handle := func(pattern string, handler http.Handler) {
+		mux.Handle(pattern, m.instrument(pattern, handler))
+	}
+	handle("/weather", recovery(weatherHandler))
+	handle("/admin/usage", recovery(adminOnly(weatherHandler, NewUsageHandler(weatherHandler))))
+	handle("/admin/cache", recovery(adminOnly(weatherHandler, NewCacheHandler(weatherHandler))))
+	handle(schemaPath, SchemaHandler{})
+	handle("/livez", LiveHandler{})
+	handle("/readyz", recovery(NewReadyHandler(weatherHandler)))
+	handle("/healthz", recovery(NewHealthHandler(weatherHandler)))
+	handle("/healthcheck", LiveHandler{})
+	handle("/metrics", m.handler())
+	handle("/metrics/weather", recovery(NewExporterHandler(weatherHandler)))
+
+	s.Server = &http.Server{
+		Addr:              cfg.ServerAddress,
+		Handler:           accessLog(mux),
+		ReadHeaderTimeout: readHeaderTimeout,
+		WriteTimeout:      writeTimeout(cfg),
+		IdleTimeout:       idleTimeout,
+	}
+	return s
+}
+
+// writeTimeout is the time allowed to handle a request and write its response. It leaves room for a call to every provider
+// for the current conditions and another for the forecast, and is at least minWriteTimeout.
+func writeTimeout(cfg *weather.Config) time.Duration {
+	d := 2 * time.Duration(len(cfg.Providers)) * cfg.ProviderTimeout
+	if d < minWriteTimeout {
+		return minWriteTimeout
+	}
+
+	return d
+}
+
+// run runs background work of the server, Shutdown waits for it to return.
+func (s *Server) run(fn func()) {
+	s.background.Add(1)
+	go func() {
+		defer s.background.Done()
+		fn()
+	}()
+}
+
+// SetAPIKeys replaces the upstream API keys used by the server.
+func (s *Server) SetAPIKeys(keys []weather.APIKey) {
+	s.weather.SetAPIKeys(keys)
+}
+
+// Reload swaps in a new config, see WeatherHandler.Reload.
+func (s *Server) Reload(cfg *weather.Config) {
+	s.weather.Reload(cfg)
+}
+
+// RejectReload records why a new config could not be loaded, see WeatherHandler.RejectReload.
+func (s *Server) RejectReload(err error) {
+	s.weather.RejectReload(err)
+}
+
+// Shutdown stops background work and gracefully shuts down the http server: it stops accepting connections and waits
+// for in-flight requests to complete until the context is done. The upstream usage is then saved, even past the deadline.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.cancel()
+	err := s.Server.Shutdown(ctx)
+	s.background.Wait()
+
+	if saveErr := s.weather.quota.Save(); saveErr != nil && err == nil {
+		err = saveErr
+	}
+
+	return err
 }
 
+// recovery turns panics into 500 responses. Secrets are redacted from the panic value before it is logged or returned.
 func recovery(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			err := recover()
 			if err != nil {
-				logrus.Error(err)
+				atomic.AddUint64(&panics, 1)
+				msg := weather.Redact(fmt.Sprint(err))
+				weather.Logger(r.Context()).Error(msg)
 
-				http.Error(w, fmt.Sprint(err), http.StatusInternalServerError)
+				http.Error(w, msg, http.StatusInternalServerError)
 				return
 			}
 
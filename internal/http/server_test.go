@@ -1,31 +1,36 @@
 package http
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"net"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/mpfrancis/weather"
 	"github.com/mpfrancis/weather/internal/mock"
+	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestServer(t *testing.T) {
-	// Get ephemeral port
+	// Get ephemeral port, the server serves on this listener so it accepts connections right away
 	listener, err := net.Listen("tcp", ":0")
 	if err != nil {
 		panic(err)
 	}
 	port := listener.Addr().(*net.TCPAddr).Port
-	assert.Equal(t, nil, listener.Close())
 
 	// Create server with mock client for panic test
-	cfg := weather.Config{ServerAddress: fmt.Sprintf(":%d", port)}
+	cfg := weather.Config{ServerAddress: fmt.Sprintf(":%d", port), AdminToken: "admin-secret"}
 	var mockClient mock.Client
 	mockClient.GetFn = func(url string) (resp *http.Response, err error) {
 		panic("PANIC TEST")
@@ -36,7 +41,7 @@ func TestServer(t *testing.T) {
 	defer s.Shutdown(ctx)
 
 	go func(s *Server) {
-		if err := s.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		if err := s.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			t.Error(err)
 		}
 	}(s)
@@ -66,6 +71,44 @@ func TestServer(t *testing.T) {
 		assert.Equal(t, "PANIC TEST\n", string(body))
 	}
 
+	// Upstream usage is only reported with the admin token, and includes the call that panicked
+	{
+		resp, err := http.Get(baseURL + "/admin/usage")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, 401, resp.StatusCode)
+
+		req, err := http.NewRequest("GET", baseURL+"/admin/usage", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer admin-secret")
+		resp, err = http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, 200, resp.StatusCode)
+		var usage weather.Usage
+		assert.Nil(t, json.NewDecoder(resp.Body).Decode(&usage))
+		assert.Equal(t, 1, usage.Minute)
+	}
+
+	// The schema of the response is published
+	{
+		resp, err := http.Get(baseURL + "/schema/v1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Equal(t, "application/schema+json", resp.Header.Get("Content-Type"))
+		var schema map[string]interface{}
+		assert.Nil(t, json.NewDecoder(resp.Body).Decode(&schema))
+		assert.Equal(t, "/schema/v1", schema["$id"])
+	}
+
 	// Ensure server still operates after panic
 	{
 		resp, err := http.Get(baseURL + "/healthcheck")
@@ -75,3 +118,83 @@ func TestServer(t *testing.T) {
 		assert.Equal(t, 200, resp.StatusCode)
 	}
 }
+
+func TestRecoveryRedactsSecrets(t *testing.T) {
+	const key = "0123456789abcdef0123456789abcdef"
+	weather.RegisterSecret(key)
+
+	var logs bytes.Buffer
+	logrus.SetOutput(&logs)
+	defer logrus.SetOutput(os.Stderr)
+
+	handler := recovery(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(fmt.Sprintf("upstream rejected key %s for http://api/weather?appid=%s", key, key))
+	}))
+
+	req, err := http.NewRequest("GET", "/weather", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, 500, rr.Code)
+	assert.Equal(t, "upstream rejected key REDACTED for http://api/weather?appid=REDACTED\n", rr.Body.String())
+	assert.Contains(t, logs.String(), "upstream rejected key REDACTED")
+	assert.NotContains(t, logs.String(), key)
+}
+
+func TestServerShutdown(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	baseURL := "http://" + listener.Addr().String()
+
+	// The upstream call of the in-flight request is held until the server is shutting down
+	started, release := make(chan struct{}), make(chan struct{})
+	var mockClient mock.Client
+	mockClient.GetFn = func(url string) (resp *http.Response, err error) {
+		close(started)
+		<-release
+		return nil, errors.New("upstream unavailable")
+	}
+
+	cfg := weather.Config{UpstreamUsageFile: filepath.Join(t.TempDir(), "usage.json")}
+	s := NewServer(&cfg, &mockClient)
+	assert.Equal(t, readHeaderTimeout, s.ReadHeaderTimeout)
+	assert.Equal(t, minWriteTimeout, s.WriteTimeout)
+	go s.Serve(listener)
+
+	responded := make(chan error, 1)
+	go func() {
+		resp, err := http.Get(baseURL + "/weather?city=Bogota&country=co")
+		if err == nil {
+			resp.Body.Close()
+		}
+		responded <- err
+	}()
+	<-started
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- s.Shutdown(context.Background()) }()
+
+	// New connections are refused while the in-flight request completes
+	time.Sleep(50 * time.Millisecond)
+	_, err = http.Get(baseURL + "/healthcheck")
+	assert.NotNil(t, err)
+	select {
+	case <-shutdown:
+		t.Fatal("Shutdown returned before the in-flight request completed")
+	default:
+	}
+
+	close(release)
+	assert.Nil(t, <-responded)
+	assert.Nil(t, <-shutdown)
+
+	// The upstream usage is saved
+	_, err = os.Stat(cfg.UpstreamUsageFile)
+	assert.Nil(t, err)
+}
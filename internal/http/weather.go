@@ -1,110 +1,290 @@
 package http
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/mpfrancis/weather"
+	"github.com/mpfrancis/weather/internal/quota"
 	"github.com/patrickmn/go-cache"
 )
 
-// WeatherHandler is the handler for the /weather endpoint.
+// WeatherHandler is the handler for the /weather endpoint, an http adapter over the weather service.
+// Rendered responses are cached along with their validators.
 type WeatherHandler struct {
-	cfg           *weather.Config
+	// hits and misses count the requests answered from the response cache and the others, evictions count the responses
+	// removed from the cache once expired. They are first for alignment.
+	hits          uint64
+	misses        uint64
+	evictions     uint64
+	cfg           atomic.Value // *weather.Config
+	service       *weather.Service
 	responseCache *cache.Cache
-	client        Clienter
+	prefetch      *prefetcher
+	quota         *quota.Accountant
+
+	// reloadErr is why the latest configuration was rejected, it is nil once a valid one is reloaded.
+	reloadMu  sync.Mutex
+	reloadErr error
+}
+
+// cachedResponse is a rendered /weather response along with the metadata needed for http caching.
+type cachedResponse struct {
+	body         []byte
+	etag         string
+	lastModified time.Time
+	stored       time.Time
 }
 
 // NewWeatherHandler returns a new instance of the weather http handler.
+// Upstream calls of the handler's service are accounted against the quota of the config.
 func NewWeatherHandler(cfg *weather.Config, client Clienter) *WeatherHandler {
-	return &WeatherHandler{
-		cfg:           cfg,
+	h := &WeatherHandler{
 		responseCache: cache.New(cfg.CacheExpirationDur, time.Minute),
-		client:        client,
+		quota:         quota.New(cfg),
 	}
+	h.cfg.Store(cfg)
+	h.responseCache.OnEvicted(func(key string, _ interface{}) {
+		if key != healthProbeKey {
+			atomic.AddUint64(&h.evictions, 1)
+		}
+	})
+	h.prefetch = newPrefetcher(h)
+	h.service = weather.NewService(cfg, h.prefetch.client(client), weather.WithQuota(h.quota))
+
+	return h
 }
 
-// ServeHTTP handles a weather request.
-// This handler will hit the open weather API and return a more human readable response.
-func (h *WeatherHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// Check cache
-	if hr, ok := h.responseCache.Get(r.URL.String()); ok {
-		if err := json.NewEncoder(w).Encode(hr); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
+// Prefetch refreshes the weather at the configured locations, and the most requested responses when prefetching is enabled,
+// in the background until the context is done.
+func (h *WeatherHandler) Prefetch(ctx context.Context) {
+	h.prefetch.run(weather.WithPriority(ctx, weather.NonEssential))
+}
 
-		w.Header().Set("Content-Type", "application/json; charset=utf-8")
-		return
+// SetAPIKeys replaces the upstream API keys used by the handler.
+func (h *WeatherHandler) SetAPIKeys(keys []weather.APIKey) {
+	h.service.SetAPIKeys(keys)
+}
+
+// Reload swaps in a new config: its API keys, default units, cache TTL policies and named locations apply to the next requests.
+// Cached responses keep their expiry. Other settings, such as the providers, quotas and prefetching, apply on restart.
+func (h *WeatherHandler) Reload(cfg *weather.Config) {
+	h.cfg.Store(cfg)
+	h.service.Reload(cfg)
+
+	h.reloadMu.Lock()
+	h.reloadErr = nil
+	h.reloadMu.Unlock()
+}
+
+// RejectReload records why a new config could not be loaded, the handler keeps its current config.
+// The config check of the handler's health fails until a valid config is reloaded.
+func (h *WeatherHandler) RejectReload(err error) {
+	h.reloadMu.Lock()
+	h.reloadErr = err
+	h.reloadMu.Unlock()
+}
+
+// config returns the current config of the handler.
+func (h *WeatherHandler) config() *weather.Config {
+	return h.cfg.Load().(*weather.Config)
+}
+
+// CacheStats are the statistics of the response cache of a weather handler, along with those of its service.
+type CacheStats struct {
+	Responses int    `json:"responses"`
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+	weather.CacheStats
+}
+
+// CacheStats returns the statistics of the handler's caches.
+func (h *WeatherHandler) CacheStats() CacheStats {
+	return CacheStats{
+		Responses:  h.responseCache.ItemCount(),
+		Hits:       atomic.LoadUint64(&h.hits),
+		Misses:     atomic.LoadUint64(&h.misses),
+		Evictions:  atomic.LoadUint64(&h.evictions),
+		CacheStats: h.service.CacheStats(),
 	}
+}
+
+// FlushCache removes the cached responses and the data cached by the service, the next requests go upstream.
+func (h *WeatherHandler) FlushCache() {
+	h.responseCache.Flush()
+	h.service.FlushCache()
+}
 
+// Usage returns the upstream calls made by the handler.
+func (h *WeatherHandler) Usage() weather.Usage {
+	return h.quota.Usage()
+}
+
+// ServeHTTP handles a weather request.
+// This handler will look up the weather with the service and return a more human readable response.
+func (h *WeatherHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Parse input parameters
-	city := r.FormValue("city")
-	if city == "" {
-		http.Error(w, "Query parameter 'city' is required", http.StatusUnprocessableEntity)
+	q, err := parseWeatherRequest(r, h.config())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
 		return
 	}
 
-	country := r.FormValue("country")
-	if country == "" {
-		http.Error(w, "Query parameter 'country' is required", http.StatusUnprocessableEntity)
-		return
+	entry := accessEntryFrom(r.Context())
+	entry.setLocation(q.Location)
+
+	if h.config().PrefetchTopN > 0 {
+		h.prefetch.record(q)
 	}
 
-	forecast := r.FormValue("forecast")
-	var forecastDay int
-	if forecast != "" {
-		day, err := strconv.Atoi(forecast)
-		forecastDay = day
-		if err != nil || day < 0 || 6 < day {
-			http.Error(w, "Query parameter 'forecast' is invalid, please provide a number between 0 and 6", http.StatusUnprocessableEntity)
-			return
-		}
+	// Check cache
+	if cached, expiration, ok := h.responseCache.GetWithExpiration(q.Key()); ok {
+		atomic.AddUint64(&h.hits, 1)
+		entry.setCacheHit()
+		writeCachedResponse(w, r, cached.(*cachedResponse), expiration)
+		return
 	}
+	atomic.AddUint64(&h.misses, 1)
 
-	// Call open weather API
-	response, err := h.client.Get(fmt.Sprintf("%s/weather?q=%s,%s&units=%s&appid=%s", h.cfg.BaseURL, city, country, h.cfg.Units, h.cfg.APIKey))
+	report, err := h.service.Lookup(r.Context(), q)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
-	// Parse the response
-	var owr weather.OpenWeatherResponse
-	if err := json.NewDecoder(response.Body).Decode(&owr); err != nil {
+	resp, err := h.store(q, report)
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	_, expiration, _ := h.responseCache.GetWithExpiration(q.Key())
 
-	hr := owr.ToHumanReadable(h.cfg.Units.Symbol())
+	writeCachedResponse(w, r, resp, expiration)
+}
 
-	if forecast != "" {
-		// Call open weather API
-		response, err := h.client.Get(fmt.Sprintf("%s/onecall?lat=%g&lon=%g&units=%s&appid=%s", h.cfg.BaseURL, owr.Coord.Lat, owr.Coord.Lon, h.cfg.Units, h.cfg.APIKey))
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
+// store renders the report and caches the response until the report expires.
+func (h *WeatherHandler) store(q weather.Query, report *weather.Report) (*cachedResponse, error) {
+	resp, err := newCachedResponse(report.Response, report.Observed)
+	if err != nil {
+		return nil, err
+	}
 
-		// Parse the response
-		var ocr weather.OneCallResponse
-		if err := json.NewDecoder(response.Body).Decode(&ocr); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
+	ttl := cache.NoExpiration
+	if !report.Expires.IsZero() {
+		// Expired reports are still cached briefly, a ttl of zero or less would never expire.
+		if ttl = time.Until(report.Expires); ttl < time.Second {
+			ttl = time.Second
 		}
+	}
+	h.responseCache.Set(q.Key(), resp, ttl)
 
-		hr.Forecast = &ocr.Daily[forecastDay]
+	return resp, nil
+}
+
+// errorStatus returns the http status of an error of the weather providers.
+// Unavailable providers result in 503 and invalid upstream responses in 502.
+func errorStatus(err error) int {
+	var upstreamErr *weather.UpstreamError
+	switch {
+	case errors.Is(err, weather.ErrQuotaExceeded), errors.Is(err, weather.ErrNoAPIKey), errors.Is(err, weather.ErrNoProvider):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, weather.ErrLocationNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, weather.ErrInvalidPayload), errors.As(err, &upstreamErr):
+		return http.StatusBadGateway
 	}
 
-	h.responseCache.Set(r.URL.String(), hr, cache.DefaultExpiration)
+	return http.StatusInternalServerError
+}
 
-	if err := json.NewEncoder(w).Encode(hr); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+// newCachedResponse renders the response and computes its validators.
+// The observation time is used as the last modified time, when it is unknown the current time is used.
+func newCachedResponse(hr *weather.HumanReadableResponse, observed time.Time) (*cachedResponse, error) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(hr); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if observed.IsZero() || observed.After(now) {
+		observed = now
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+
+	return &cachedResponse{
+		body:         buf.Bytes(),
+		etag:         `"` + hex.EncodeToString(sum[:16]) + `"`,
+		lastModified: observed.UTC().Truncate(time.Second),
+		stored:       now,
+	}, nil
+}
+
+// writeCachedResponse writes the response with its caching headers.
+// Conditional requests matching the response are answered with 304 Not Modified.
+func writeCachedResponse(w http.ResponseWriter, r *http.Request, resp *cachedResponse, expiration time.Time) {
+	now := time.Now()
+
+	w.Header().Set("ETag", resp.etag)
+	w.Header().Set("Last-Modified", resp.lastModified.Format(http.TimeFormat))
+	w.Header().Set("Age", strconv.Itoa(int(now.Sub(resp.stored)/time.Second)))
+	if expiration.IsZero() {
+		w.Header().Set("Cache-Control", "public")
+	} else {
+		maxAge := int(expiration.Sub(now) / time.Second)
+		if maxAge < 0 {
+			maxAge = 0
+		}
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", maxAge))
+	}
+
+	if notModified(r, resp) {
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(len(resp.body)))
+	w.Header().Set("Link", "<"+schemaPath+`>; rel="describedby"`)
+	w.Write(resp.body)
+}
+
+// notModified evaluates the request's If-None-Match and If-Modified-Since preconditions against the response.
+// If-Modified-Since is only considered when If-None-Match is not present.
+func notModified(r *http.Request, resp *cachedResponse) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etagMatches(inm, resp.etag)
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		t, err := http.ParseTime(ims)
+		return err == nil && !resp.lastModified.After(t)
+	}
+
+	return false
+}
+
+// etagMatches reports whether the If-None-Match header value matches the etag.
+// Weak comparison is used, as required for If-None-Match.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
 }
@@ -2,9 +2,13 @@ package http
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
 	"time"
@@ -56,7 +60,7 @@ var cases = []testCase{
 			"name": "Bogotá"
 		}
 		`,
-		expectedResponse:     `{"location_name":"Bogotá, CO","temperature":"20 °C","wind":"Light breeze, 2.6 m/s, southwest","cloudiness":"scattered clouds","pressure":"1025 hpa","humidity":"37%","sunrise":"05:57","sunset":"17:48","geo_coordinates":"[4.61, -74.08]","requested_time":"` + time.Now().Format("2006-01-02 15:04:05") + `"}` + "\n",
+		expectedResponse:     `{"location_name":"Bogotá, CO","temperature":"20 °C","wind":"Light breeze, 2.6 m/s, southwest","cloudiness":"scattered clouds","pressure":"1025 hpa","humidity":"37%","sunrise":"05:57","sunset":"17:48","geo_coordinates":"[4.61, -74.08]","requested_time":"` + time.Now().Format("2006-01-02 15:04:05") + `","provider":"openweather"}` + "\n",
 		expectedResponseCode: 200,
 		invoked:              true,
 	},
@@ -387,7 +391,7 @@ var cases = []testCase{
 			]
 		}
 	`,
-		expectedResponse:     `{"location_name":"Bogotá, CO","temperature":"20 °C","wind":"Light breeze, 2.6 m/s, southwest","cloudiness":"scattered clouds","pressure":"1025 hpa","humidity":"37%","sunrise":"05:57","sunset":"17:48","geo_coordinates":"[4.61, -74.08]","requested_time":"` + time.Now().Format("2006-01-02 15:04:05") + `","forecast":{"dt":1608825600,"sunrise":1608807628,"sunset":1608850304,"temp":{"day":19.31,"min":8.89,"max":19.68,"night":11.64,"eve":14.57,"morn":9.16},"feels_like":{"day":19.12,"night":11.24,"eve":14.99,"morn":7.93},"pressure":1014,"humidity":56,"dew_point":10.32,"wind_speed":0.45,"wind_deg":190,"weather":[{"id":500,"main":"Rain","description":"light rain","icon":"10d"}],"clouds":31,"pop":0.97,"rain":6.42,"uvi":11.99}}` + "\n",
+		expectedResponse:     `{"location_name":"Bogotá, CO","temperature":"20 °C","wind":"Light breeze, 2.6 m/s, southwest","cloudiness":"scattered clouds","pressure":"1025 hpa","humidity":"37%","sunrise":"05:57","sunset":"17:48","geo_coordinates":"[4.61, -74.08]","requested_time":"` + time.Now().Format("2006-01-02 15:04:05") + `","provider":"openweather","forecast_provider":"openweather","forecast":{"time":"2020-12-24T16:00:00Z","temp_min":8.89,"temp_max":19.68,"pressure":1014,"humidity":56,"cloud_cover":31,"wind_speed":0.45,"wind_deg":190,"precipitation_probability":0.97,"precipitation":6.42,"uv_index":11.99,"condition":"Rain","description":"light rain","sunrise":"2020-12-24T11:00:28Z","sunset":"2020-12-24T22:51:44Z"}}` + "\n",
 		expectedResponseCode: 200,
 		invoked:              true,
 	},
@@ -424,7 +428,23 @@ var cases = []testCase{
 			"name": "Bogotá"
 		}
 		`,
-		expectedResponse:     `{"location_name":"Bogotá, CO","temperature":"20 °C","wind":"Light breeze, 2.6 m/s, southwest","cloudiness":"scattered clouds","pressure":"1025 hpa","humidity":"37%","sunrise":"05:57","sunset":"17:48","geo_coordinates":"[4.61, -74.08]","requested_time":"` + time.Now().Format("2006-01-02 15:04:05") + `"}` + "\n",
+		expectedResponse:     `{"location_name":"Bogotá, CO","temperature":"20 °C","wind":"Light breeze, 2.6 m/s, southwest","cloudiness":"scattered clouds","pressure":"1025 hpa","humidity":"37%","sunrise":"05:57","sunset":"17:48","geo_coordinates":"[4.61, -74.08]","requested_time":"` + time.Now().Format("2006-01-02 15:04:05") + `","provider":"openweather"}` + "\n",
+		expectedResponseCode: 200,
+		invoked:              false,
+	},
+
+	// Cache hit for reordered and differently cased parameters
+	testCase{
+		url:                  "/weather?country=CO&city=bogota",
+		expectedResponse:     `{"location_name":"Bogotá, CO","temperature":"20 °C","wind":"Light breeze, 2.6 m/s, southwest","cloudiness":"scattered clouds","pressure":"1025 hpa","humidity":"37%","sunrise":"05:57","sunset":"17:48","geo_coordinates":"[4.61, -74.08]","requested_time":"` + time.Now().Format("2006-01-02 15:04:05") + `","provider":"openweather"}` + "\n",
+		expectedResponseCode: 200,
+		invoked:              false,
+	},
+
+	// Cache hit with default parameters given explicitly and unknown parameters added
+	testCase{
+		url:                  "/weather?city=%20Bogota%20&country=co&units=metric&lang=EN&utm=x",
+		expectedResponse:     `{"location_name":"Bogotá, CO","temperature":"20 °C","wind":"Light breeze, 2.6 m/s, southwest","cloudiness":"scattered clouds","pressure":"1025 hpa","humidity":"37%","sunrise":"05:57","sunset":"17:48","geo_coordinates":"[4.61, -74.08]","requested_time":"` + time.Now().Format("2006-01-02 15:04:05") + `","provider":"openweather"}` + "\n",
 		expectedResponseCode: 200,
 		invoked:              false,
 	},
@@ -445,6 +465,54 @@ var cases = []testCase{
 		invoked:              false,
 	},
 
+	// Query parameter country missing for a zip code
+	testCase{
+		url:                  "/weather?zip=10001",
+		expectedResponse:     "Query parameter 'country' is required\n",
+		expectedResponseCode: 422,
+		invoked:              false,
+	},
+
+	// Query parameter lon missing
+	testCase{
+		url:                  "/weather?lat=4.61",
+		expectedResponse:     "Query parameters 'lat' and 'lon' are invalid, please provide a latitude between -90 and 90 and a longitude between -180 and 180\n",
+		expectedResponseCode: 422,
+		invoked:              false,
+	},
+
+	// Coordinates out of range
+	testCase{
+		url:                  "/weather?lat=91&lon=-74.08",
+		expectedResponse:     "Query parameters 'lat' and 'lon' are invalid, please provide a latitude between -90 and 90 and a longitude between -180 and 180\n",
+		expectedResponseCode: 422,
+		invoked:              false,
+	},
+
+	// Invalid country value
+	testCase{
+		url:                  "/weather?city=Bogota&country=colombia",
+		expectedResponse:     "Query parameter 'country' is invalid, please provide a two letter ISO 3166 country code\n",
+		expectedResponseCode: 422,
+		invoked:              false,
+	},
+
+	// Invalid units value
+	testCase{
+		url:                  "/weather?city=Bogota&country=co&units=kelvin",
+		expectedResponse:     "Query parameter 'units' is invalid, use: standard, metric, imperial\n",
+		expectedResponseCode: 422,
+		invoked:              false,
+	},
+
+	// Invalid lang value
+	testCase{
+		url:                  "/weather?city=Bogota&country=co&lang=e1",
+		expectedResponse:     "Query parameter 'lang' is invalid, please provide a language code such as 'en' or 'pt_br'\n",
+		expectedResponseCode: 422,
+		invoked:              false,
+	},
+
 	// Invalid forecast value
 	testCase{
 		url:                  "/weather?city=Bogota&country=co&forecast=7",
@@ -472,10 +540,11 @@ var cases = []testCase{
 
 func TestWeatherHandler(t *testing.T) {
 	cfg := weather.Config{Units: weather.Metric}
-	handler := NewWeatherHandler(&cfg, nil)
+	mockClient := mock.Client{}
+	handler := NewWeatherHandler(&cfg, &mockClient)
 
 	for i := range cases {
-		mockClient := mock.Client{}
+		mockClient.GetInvoked = false
 		mockClient.GetFn = func(url string) (resp *http.Response, err error) {
 			switch {
 			case strings.Contains(url, "/onecall?"):
@@ -495,8 +564,6 @@ func TestWeatherHandler(t *testing.T) {
 			return nil, nil
 		}
 
-		handler.client = &mockClient
-
 		req, err := http.NewRequest("GET", cases[i].url, nil)
 		if err != nil {
 			t.Fatal(err)
@@ -508,5 +575,369 @@ func TestWeatherHandler(t *testing.T) {
 		assert.Equal(t, cases[i].expectedResponseCode, rr.Code)
 		assert.Equal(t, cases[i].expectedResponse, rr.Body.String())
 		assert.Equal(t, cases[i].invoked, mockClient.GetInvoked)
+		if rr.Code == http.StatusOK {
+			assert.Equal(t, "application/json; charset=utf-8", rr.Header().Get("Content-Type"))
+			assert.Equal(t, `</schema/v1>; rel="describedby"`, rr.Header().Get("Link"))
+		}
+	}
+}
+
+func TestWeatherHandlerCacheTTL(t *testing.T) {
+	cfg := weather.Config{
+		Units: weather.Metric,
+		CacheTTLs: map[weather.DataType]weather.TTLPolicy{
+			weather.CurrentData: {TTL: 10 * time.Minute, FromObservation: true, MinTTL: time.Minute},
+		},
+	}
+	mockClient := mock.Client{}
+	handler := NewWeatherHandler(&cfg, &mockClient)
+
+	mockClient.GetFn = func(url string) (resp *http.Response, err error) {
+		body := fmt.Sprintf(`{"dt": %d, "name": "Bogotá", "sys": {"country": "CO"}}`, time.Now().Add(-8*time.Minute).Unix())
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(body))}, nil
+	}
+
+	req, err := http.NewRequest("GET", "/weather?city=Bogota&country=co", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, 200, rr.Code)
+
+	// The observation is eight minutes old, so it is only cached for the remaining two minutes of its TTL
+	_, expiration, ok := handler.responseCache.GetWithExpiration("bogota|CO|metric|en|-1")
+	assert.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(2*time.Minute), expiration, 5*time.Second)
+}
+
+func TestWeatherHandlerConditionalRequests(t *testing.T) {
+	cfg := weather.Config{Units: weather.Metric, CacheExpirationDur: 10 * time.Minute}
+	mockClient := mock.Client{}
+	handler := NewWeatherHandler(&cfg, &mockClient)
+
+	observed := time.Now().Add(-time.Minute).Truncate(time.Second)
+	mockClient.GetFn = func(url string) (resp *http.Response, err error) {
+		body := fmt.Sprintf(`{"dt": %d, "name": "Bogotá", "sys": {"country": "CO"}}`, observed.Unix())
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(body))}, nil
+	}
+
+	serve := func(header, value string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("GET", "/weather?city=Bogota&country=co", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Initial request carries validators and a max-age derived from the cache TTL
+	rr := serve("", "")
+	assert.Equal(t, 200, rr.Code)
+	etag := rr.Header().Get("ETag")
+	assert.Regexp(t, `^"[0-9a-f]{32}"$`, etag)
+	assert.Equal(t, observed.UTC().Format(http.TimeFormat), rr.Header().Get("Last-Modified"))
+	assert.Regexp(t, `^public, max-age=(599|600)$`, rr.Header().Get("Cache-Control"))
+	assert.Equal(t, "0", rr.Header().Get("Age"))
+
+	// Matching entity tags are not modified
+	rr = serve("If-None-Match", etag)
+	assert.Equal(t, 304, rr.Code)
+	assert.Equal(t, "", rr.Body.String())
+	assert.Equal(t, etag, rr.Header().Get("ETag"))
+
+	rr = serve("If-None-Match", `"other", W/`+etag)
+	assert.Equal(t, 304, rr.Code)
+
+	// A different entity tag gets the full response, even when If-Modified-Since would match
+	req, err := http.NewRequest("GET", "/weather?city=Bogota&country=co", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("If-None-Match", `"other"`)
+	req.Header.Set("If-Modified-Since", time.Now().UTC().Format(http.TimeFormat))
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, 200, rr.Code)
+
+	// Modification dates
+	rr = serve("If-Modified-Since", observed.UTC().Format(http.TimeFormat))
+	assert.Equal(t, 304, rr.Code)
+
+	rr = serve("If-Modified-Since", observed.Add(-time.Hour).UTC().Format(http.TimeFormat))
+	assert.Equal(t, 200, rr.Code)
+	assert.Equal(t, "application/json; charset=utf-8", rr.Header().Get("Content-Type"))
+}
+
+func TestWeatherHandlerCoordinateCache(t *testing.T) {
+	cfg := weather.Config{Units: weather.Metric}
+	mockClient := mock.Client{}
+	handler := NewWeatherHandler(&cfg, &mockClient)
+
+	oneCallStarted := make(chan struct{}, 1)
+	var parallel bool
+	mockClient.GetFn = func(url string) (resp *http.Response, err error) {
+		body := `{"daily": [{"dt": 1608825600}, {"dt": 1608912000}]}`
+		switch {
+		case strings.Contains(url, "/onecall?"):
+			assert.Contains(t, url, "lat=4.61")
+			assert.Contains(t, url, "lon=-74.08")
+			oneCallStarted <- struct{}{}
+		case strings.Contains(url, "/weather?"):
+			body = `{"coord": {"lon": -74.08, "lat": 4.61}, "name": "Bogotá", "sys": {"country": "CO"}}`
+			if strings.Contains(url, "lang=es") {
+				// The coordinates were learned by the first request, the forecast is requested without waiting for current conditions
+				select {
+				case <-oneCallStarted:
+					parallel = true
+				case <-time.After(5 * time.Second):
+				}
+			}
+		}
+
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(body))}, nil
+	}
+
+	// The second request misses the service's cached data, but not the location's coordinates
+	for _, u := range []string{"/weather?city=Bogota&country=co&forecast=0", "/weather?city=Bogota&country=co&forecast=1&lang=es"} {
+		req, err := http.NewRequest("GET", u, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		assert.Equal(t, 200, rr.Code)
+
+		// Discard the signal of a forecast requested after current conditions
+		select {
+		case <-oneCallStarted:
+		default:
+		}
+	}
+
+	assert.True(t, parallel)
+}
+
+func TestWeatherHandlerQuotaExceeded(t *testing.T) {
+	cfg := weather.Config{Units: weather.Metric, UpstreamCallsPerMinute: 1}
+	mockClient := mock.Client{}
+	handler := NewWeatherHandler(&cfg, &mockClient)
+
+	mockClient.GetFn = func(url string) (resp *http.Response, err error) {
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(`{"name": "Bogotá", "sys": {"country": "CO"}}`))}, nil
+	}
+
+	// The second request misses the cache and is refused, the plan allows only one call per minute
+	for i, lang := range []string{"en", "es"} {
+		req, err := http.NewRequest("GET", "/weather?city=Bogota&country=co&lang="+lang, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		assert.Equal(t, []int{200, 503}[i], rr.Code)
+	}
+
+	assert.Equal(t, 1, handler.Usage().Minute)
+}
+
+func TestWeatherHandlerAPIKeyFailover(t *testing.T) {
+	cfg := weather.Config{Units: weather.Metric, APIKeys: []weather.APIKey{{Key: "revoked", Weight: 1}, {Key: "valid", Weight: 1}}, APIKeyCooldown: time.Hour}
+	mockClient := mock.Client{}
+	handler := NewWeatherHandler(&cfg, &mockClient)
+
+	var keys []string
+	mockClient.GetFn = func(url string) (resp *http.Response, err error) {
+		if strings.Contains(url, "appid=revoked") {
+			keys = append(keys, "revoked")
+			return &http.Response{StatusCode: 401, Body: ioutil.NopCloser(strings.NewReader(`{"cod": 401}`))}, nil
+		}
+
+		keys = append(keys, "valid")
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(`{"name": "Bogotá", "sys": {"country": "CO"}}`))}, nil
+	}
+
+	serve := func(city string) int {
+		req, err := http.NewRequest("GET", "/weather?country=co&city="+city, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
 	}
+
+	// The revoked key is tried once, then taken out of service
+	assert.Equal(t, 200, serve("bogota"))
+	assert.Equal(t, 200, serve("medellin"))
+	assert.Equal(t, 200, serve("cali"))
+	assert.Equal(t, []string{"revoked", "valid", "valid", "valid"}, keys)
+
+	// New keys are accepted without a restart
+	handler.SetAPIKeys([]weather.APIKey{{Key: "revoked", Weight: 1}})
+	assert.Equal(t, 503, serve("pasto"))
+}
+
+func TestWeatherHandlerProviderFailover(t *testing.T) {
+	cfg := weather.Config{
+		Units:                 weather.Metric,
+		BaseURL:               "http://openweather",
+		OpenMeteoURL:          "http://openmeteo",
+		OpenMeteoGeocodingURL: "http://geocoding",
+		Providers:             []string{weather.OpenWeatherProvider, weather.OpenMeteoProvider},
+		ProviderTimeout:       time.Second,
+	}
+	mockClient := mock.Client{}
+	handler := NewWeatherHandler(&cfg, &mockClient)
+
+	mockClient.GetFn = func(url string) (resp *http.Response, err error) {
+		switch {
+		case strings.HasPrefix(url, "http://openweather"):
+			return &http.Response{StatusCode: 500, Body: ioutil.NopCloser(strings.NewReader(`{"cod": 500}`))}, nil
+		case strings.HasPrefix(url, "http://geocoding"):
+			return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(`{"results": [{"name": "Bogotá", "latitude": 4.61, "longitude": -74.08, "country_code": "CO"}]}`))}, nil
+		default:
+			return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(`{"latitude": 4.61, "longitude": -74.08, "current": {"time": 1605182400, "temperature_2m": 14}}`))}, nil
+		}
+	}
+
+	req, err := http.NewRequest("GET", "/weather?city=Bogota&country=co", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, 200, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"provider":"openmeteo"`)
+}
+
+func TestWeatherHandlerEnsemble(t *testing.T) {
+	cfg := weather.Config{
+		Units:                 weather.Metric,
+		BaseURL:               "http://openweather",
+		OpenMeteoURL:          "http://openmeteo",
+		OpenMeteoGeocodingURL: "http://geocoding",
+		Providers:             []string{weather.OpenWeatherProvider, weather.OpenMeteoProvider},
+	}
+	mockClient := mock.Client{}
+	handler := NewWeatherHandler(&cfg, &mockClient)
+
+	mockClient.GetFn = func(url string) (resp *http.Response, err error) {
+		body := `{"daily": {"time": [1608825600, 1608912000], "temperature_2m_max": [19, 18], "precipitation_probability_max": [10, 90]}}`
+		switch {
+		case strings.HasPrefix(url, "http://openweather/weather"):
+			body = `{"coord": {"lon": -74.08, "lat": 4.61}, "name": "Bogotá", "sys": {"country": "CO"}}`
+		case strings.HasPrefix(url, "http://openweather/onecall"):
+			body = `{"daily": [{"dt": 1608825600, "temp": {"max": 20}, "pop": 0.2}, {"dt": 1608912000, "temp": {"max": 19}, "pop": 0.1}]}`
+		case strings.HasPrefix(url, "http://geocoding"):
+			body = `{"results": [{"name": "Bogotá", "latitude": 4.61, "longitude": -74.08, "country_code": "CO"}]}`
+		}
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(body))}, nil
+	}
+
+	req, err := http.NewRequest("GET", "/weather?city=Bogota&country=co&forecast=1&ensemble=true", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	var hr weather.HumanReadableResponse
+	assert.Equal(t, 200, rr.Code)
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &hr))
+	assert.Equal(t, "openweather", hr.ForecastProvider)
+	assert.Equal(t, 19.0, hr.Forecast.TempMax)
+	assert.Equal(t, []string{"openweather", "openmeteo"}, hr.Ensemble.Providers)
+	assert.Equal(t, weather.Spread{Value: 18.5, Min: 18, Max: 19}, hr.Ensemble.TempMax)
+	assert.Equal(t, []string{"providers disagree on rain"}, hr.Ensemble.Disagreements)
+}
+
+type upstreamErrorCase struct {
+	url                  string
+	status               int
+	body                 string
+	forecastBody         string
+	expectedResponseCode int
+}
+
+func TestWeatherHandlerUpstreamErrors(t *testing.T) {
+	cases := []upstreamErrorCase{
+		// Unknown city
+		{"/weather?city=Atlantis&country=gr", 404, `{"cod": "404", "message": "city not found"}`, "", 404},
+
+		// Upstream error payload sent with a successful status
+		{"/weather?city=Bogota&country=co", 200, `{"cod": "500", "message": "internal error"}`, "", 502},
+
+		// Humidity outside its physical range
+		{"/weather?city=Bogota&country=co", 200, `{"name": "Bogotá", "main": {"humidity": 500}}`, "", 502},
+
+		// Negative wind direction
+		{"/weather?city=Bogota&country=co", 200, `{"name": "Bogotá", "wind": {"deg": -10}}`, "", 502},
+
+		// Forecast without days
+		{"/weather?city=Bogota&country=co&forecast=0", 200, `{"name": "Bogotá"}`, `{"daily": []}`, 502},
+
+		// Forecast with fewer days than requested
+		{"/weather?city=Bogota&country=co&forecast=3", 200, `{"name": "Bogotá"}`, `{"daily": [{"dt": 1608825600}]}`, 502},
+	}
+
+	for _, c := range cases {
+		cfg := weather.Config{Units: weather.Metric}
+		mockClient := mock.Client{}
+		handler := NewWeatherHandler(&cfg, &mockClient)
+
+		mockClient.GetFn = func(url string) (resp *http.Response, err error) {
+			if strings.Contains(url, "/onecall?") {
+				return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(c.forecastBody))}, nil
+			}
+			return &http.Response{StatusCode: c.status, Body: ioutil.NopCloser(strings.NewReader(c.body))}, nil
+		}
+
+		req, err := http.NewRequest("GET", c.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		assert.Equal(t, c.expectedResponseCode, rr.Code, c.body+c.forecastBody)
+	}
+}
+
+func TestWeatherHandlerRedactsAPIKey(t *testing.T) {
+	const key = "0123456789abcdef0123456789abcdef"
+	cfg := weather.Config{Units: weather.Metric, APIKey: key}
+	mockClient := mock.Client{}
+	handler := NewWeatherHandler(&cfg, &mockClient)
+
+	mockClient.GetFn = func(u string) (resp *http.Response, err error) {
+		return nil, &url.Error{Op: "Get", URL: u, Err: errors.New("connection refused")}
+	}
+
+	req, err := http.NewRequest("GET", "/weather?city=Bogota&country=co", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, 500, rr.Code)
+	assert.Contains(t, rr.Body.String(), "appid=REDACTED")
+	assert.NotContains(t, rr.Body.String(), key)
+	assert.Len(t, mockClient.URLs, 1)
+	assert.Contains(t, mockClient.URLs[0], "appid=REDACTED")
+	assert.NotContains(t, mockClient.URLs[0], key)
 }
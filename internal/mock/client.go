@@ -1,15 +1,31 @@
 package mock
 
-import "net/http"
+import (
+	"net/http"
+	"sync"
+
+	"github.com/mpfrancis/weather"
+)
 
 // Client is the mock client
 type Client struct {
 	GetFn      func(url string) (resp *http.Response, err error)
 	GetInvoked bool
+
+	// URLs holds the requested URLs with secrets redacted, so they can be shown in test failures.
+	URLs []string
+
+	mu sync.Mutex
 }
 
-// Get is a mock function for the Get function on net/http.Client
-func (c *Client) Get(url string) (*http.Response, error) {
+// Do is a mock function for the Do function on net/http.Client, requests are answered by GetFn.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	url := req.URL.String()
+
+	c.mu.Lock()
 	c.GetInvoked = true
+	c.URLs = append(c.URLs, weather.Redact(url))
+	c.mu.Unlock()
+
 	return c.GetFn(url)
 }
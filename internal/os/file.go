@@ -0,0 +1,103 @@
+package os
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// readConfigFile reads a YAML, TOML or JSON config file, chosen by its extension, into setting values by key.
+// Nested tables are flattened into dotted keys, lists are joined with commas and the named locations with semicolons,
+// so that file values take the form of environment variables. Keys that are not settings are errors.
+func readConfigFile(path string) (map[string]string, []error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, []error{fmt.Errorf("Unable to read config file: %w", err)}
+	}
+
+	var tree map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &tree)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &tree)
+	case ".toml":
+		err = toml.Unmarshal(data, &tree)
+	default:
+		return nil, []error{fmt.Errorf("Unable to read config file %s, use a .yaml, .yml, .toml or .json file", path)}
+	}
+	if err != nil {
+		return nil, []error{fmt.Errorf("Unable to parse config file %s: %v", path, err)}
+	}
+
+	values := make(map[string]string)
+	var errs []error
+	flatten(tree, "", values, func(key string) {
+		errs = append(errs, fmt.Errorf("Unknown setting %s in %s", key, path))
+	})
+
+	return values, errs
+}
+
+// flatten stores the settings of the tree in values by dotted key, reporting the keys that are not settings.
+func flatten(tree map[string]interface{}, prefix string, values map[string]string, unknown func(key string)) {
+	for _, k := range sortedKeys(tree) {
+		key := prefix + k
+		if _, ok := findSetting(key); ok {
+			values[key] = formatValue(tree[k])
+			continue
+		}
+
+		if table, ok := tree[k].(map[string]interface{}); ok {
+			flatten(table, key+".", values, unknown)
+			continue
+		}
+
+		unknown(key)
+	}
+}
+
+// formatValue returns a value of a config file in the form of an environment variable.
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []interface{}:
+		items := make([]string, len(v))
+		for i := range v {
+			items[i] = formatValue(v[i])
+		}
+		return strings.Join(items, ",")
+	case map[string]interface{}:
+		pairs := make([]string, 0, len(v))
+		for _, k := range sortedKeys(v) {
+			pairs = append(pairs, k+"="+formatValue(v[k]))
+		}
+		return strings.Join(pairs, ";")
+	}
+
+	return fmt.Sprint(v)
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
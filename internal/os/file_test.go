@@ -0,0 +1,176 @@
+package os
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mpfrancis/weather"
+	"github.com/stretchr/testify/assert"
+)
+
+const yamlConfig = `
+# Weather API
+providers: [openweather, "openmeteo"]
+openweather:
+  base_url: http://api.openweathermap.org/data/2.5
+  api_keys:
+    - abc123:3
+    - 'def456'   # fallback
+units: imperial
+cache:
+  expiration: 5m
+  ttl:
+    current: 10m
+    from_observation: true
+upstream:
+  calls_per_minute: 30
+locations:
+  home: Bogota,CO
+  "office": 4.61,-74.08
+`
+
+const tomlConfig = `
+# Weather API
+providers = ["openweather", "openmeteo"]
+units = "imperial"
+locations.home = "Bogota,CO"
+
+[openweather]
+base_url = "http://api.openweathermap.org/data/2.5"
+api_keys = [
+  "abc123:3",
+  'def456', # fallback
+]
+
+[cache]
+expiration = "5m"
+ttl.current = "10m"
+ttl.from_observation = true
+
+[upstream]
+calls_per_minute = 30
+
+[locations]
+office = "4.61,-74.08"
+`
+
+const jsonConfig = `{
+  "providers": ["openweather", "openmeteo"],
+  "openweather": {"base_url": "http://api.openweathermap.org/data/2.5", "api_keys": ["abc123:3", "def456"]},
+  "units": "imperial",
+  "cache": {"expiration": "5m", "ttl": {"current": "10m", "from_observation": true}},
+  "upstream": {"calls_per_minute": 30},
+  "locations": {"home": "Bogota,CO", "office": "4.61,-74.08"}
+}`
+
+// writeFile writes the content to a file with the given name in a temporary directory of the test.
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLoadFile(t *testing.T) {
+	expected := weather.Config{
+		Providers:             []string{weather.OpenWeatherProvider, weather.OpenMeteoProvider},
+		ProviderTimeout:       5 * time.Second,
+		OpenMeteoURL:          "https://api.open-meteo.com/v1",
+		OpenMeteoGeocodingURL: "https://geocoding-api.open-meteo.com/v1",
+		BaseURL:               "http://api.openweathermap.org/data/2.5",
+		APIKeys:               []weather.APIKey{{Key: "abc123", Weight: 3}, {Key: "def456", Weight: 1}},
+		APIKeyCooldown:        5 * time.Minute,
+		APIKeyRefresh:         5 * time.Minute,
+		Units:                 weather.Imperial,
+		LogLevel:              "info",
+		LogFormat:             "json",
+		ServerAddress:         ":10000",
+		ShutdownTimeout:       20 * time.Second,
+		CacheExpiration:       "5m",
+		CacheExpirationDur:    5 * time.Minute,
+		CacheTTLs: map[weather.DataType]weather.TTLPolicy{
+			weather.CurrentData:  {TTL: 10 * time.Minute, FromObservation: true},
+			weather.ForecastData: {TTL: 5 * time.Minute},
+		},
+		Locations: map[string]weather.Location{
+			"home":   {City: "bogota", Country: "CO"},
+			"office": {Coord: &weather.Coord{Lat: 4.61, Lon: -74.08}},
+		},
+		UpstreamCallsPerMinute: 30,
+		UpstreamQuotaReserve:   0.1,
+		PrefetchLead:           30 * time.Second,
+		PrefetchShare:          0.2,
+	}
+
+	files := map[string]string{"weather.yaml": yamlConfig, "weather.toml": tomlConfig, "weather.json": jsonConfig}
+	for name, content := range files {
+		cfg, err := Load(Options{File: writeFile(t, name, content)})
+		assert.Nil(t, err, name)
+		assert.Equal(t, &expected, cfg, name)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeFile(t, "weather.yaml", yamlConfig)
+	env := map[string]string{envConfigFile: path, envUnits: "standard", envCacheExpiration: "1m"}
+	for k, v := range env {
+		if err := os.Setenv(k, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer func() {
+		for k := range env {
+			os.Unsetenv(k)
+		}
+	}()
+
+	// Flags take precedence over the environment, which takes precedence over the file
+	cfg, err := Load(Options{Flags: map[string]string{"units": "metric"}})
+	assert.Nil(t, err)
+	assert.Equal(t, weather.Metric, cfg.Units)
+	assert.Equal(t, time.Minute, cfg.CacheExpirationDur)
+	assert.Equal(t, 30, cfg.UpstreamCallsPerMinute)
+
+	// Invalid file values are reported with the file they come from
+	os.Setenv(envConfigFile, writeFile(t, "weather.toml", "units = \"metric\"\nprefetch.lead = \"soon\"\ncache.ttl.hourly = \"1h\"\n"))
+	_, err = Load(Options{})
+	assert.Equal(t, Errors{
+		errors.New("Unknown setting cache.ttl.hourly in " + os.Getenv(envConfigFile)),
+		errMissingBaseURL,
+		errMissingAPIKey,
+		errors.New(`prefetch.lead in ` + os.Getenv(envConfigFile) + ` "soon" is invalid, please provide a duration such as 90s or 5m`),
+	}, err)
+}
+
+type parseCase struct {
+	name     string
+	content  string
+	expected string
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := []parseCase{
+		{"weather.yaml", "cache:\n  expiration: 5m\n    min: 1m\n", "yaml: line 3: mapping values are not allowed in this context"},
+		{"weather.yaml", "providers: [openweather\n", "yaml: line 1: did not find expected ',' or ']'"},
+		{"weather.yaml", "units: metric\nunits: imperial\n", "yaml: unmarshal errors:\n  line 2: mapping key \"units\" already defined at line 1"},
+		{"weather.toml", "units = metric\n", "toml: line 1 (last key \"units\"): expected value but found \"metric\" instead"},
+		{"weather.toml", "units = \"metric\"\n[units]\n", "toml: line 2: Key 'units' has already been defined."},
+		{"weather.json", "{\"units\": }", "invalid character '}' looking for beginning of value"},
+	}
+
+	for i := range cases {
+		path := writeFile(t, cases[i].name, cases[i].content)
+		_, err := Load(Options{File: path})
+		assert.True(t, errors.Is(err, errMissingBaseURL), cases[i].name)
+		assert.Equal(t, "Unable to parse config file "+path+": "+cases[i].expected, err.(Errors)[0].Error(), cases[i].name)
+	}
+
+	_, err := Load(Options{File: "weather.ini"})
+	assert.Equal(t, "Unable to read config file: open weather.ini: no such file or directory", err.(Errors)[0].Error())
+}
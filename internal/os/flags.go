@@ -0,0 +1,46 @@
+package os
+
+import "flag"
+
+// Options are the sources of the configuration besides the environment.
+type Options struct {
+	// File is the path of the config file, WEATHER_CONFIG_FILE is used when it is empty.
+	File string
+	// Flags are the values given on the command line, by setting key.
+	Flags map[string]string
+	// APIKeySource provides the open weather API key when none of WEATHER_APIKEY, WEATHER_APIKEY_FILE
+	// and WEATHER_APIKEY_COMMAND is set.
+	APIKeySource SecretSource
+}
+
+// RegisterFlags defines --config and a flag for every setting on the flag set.
+// The values given on the command line are recorded in the options as the flag set is parsed.
+func RegisterFlags(fs *flag.FlagSet, opts *Options) {
+	fs.StringVar(&opts.File, "config", "", "config file in YAML, TOML or JSON format ("+envConfigFile+")")
+	for _, s := range settings {
+		fs.Var(&settingFlag{key: s.key, opts: opts}, flagName(s.key), s.usage+" ("+s.env+")")
+	}
+}
+
+// settingFlag is the flag.Value of a setting, it records the value given on the command line in the options.
+type settingFlag struct {
+	key  string
+	opts *Options
+}
+
+func (f *settingFlag) String() string {
+	if f.opts == nil {
+		return ""
+	}
+
+	return f.opts.Flags[f.key]
+}
+
+func (f *settingFlag) Set(value string) error {
+	if f.opts.Flags == nil {
+		f.opts.Flags = make(map[string]string)
+	}
+	f.opts.Flags[f.key] = value
+
+	return nil
+}
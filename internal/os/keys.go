@@ -0,0 +1,77 @@
+package os
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/mpfrancis/weather"
+)
+
+// ParseAPIKeys parses a list of API keys separated by commas or new lines.
+// Each key may be followed by a colon and its weight, e.g. "abc123:3,def456". Keys default to a weight of one.
+// Blank lines and lines starting with # are ignored.
+func ParseAPIKeys(s string) ([]weather.APIKey, error) {
+	var keys []weather.APIKey
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		for _, field := range strings.Split(line, ",") {
+			field = strings.TrimSpace(field)
+			if field == "" {
+				continue
+			}
+
+			key := weather.APIKey{Key: field, Weight: 1}
+			if i := strings.LastIndex(field, ":"); i >= 0 {
+				weight, err := strconv.Atoi(field[i+1:])
+				if err != nil || weight < 1 {
+					return nil, fmt.Errorf("Invalid API key weight %q, please provide a positive number", field[i+1:])
+				}
+				key = weather.APIKey{Key: field[:i], Weight: weight}
+			}
+
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+// ReadAPIKeys reads a list of API keys in the format accepted by ParseAPIKeys from a file.
+func ReadAPIKeys(path string) ([]weather.APIKey, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseAPIKeys(string(b))
+}
+
+// getAPIKeys combines the single API key, the list of API keys and the keys in the keys file.
+func getAPIKeys(apiKey, list, file string) ([]weather.APIKey, error) {
+	var keys []weather.APIKey
+	if apiKey != "" {
+		keys = append(keys, weather.APIKey{Key: apiKey, Weight: 1})
+	}
+
+	listKeys, err := ParseAPIKeys(list)
+	if err != nil {
+		return nil, err
+	}
+	keys = append(keys, listKeys...)
+
+	if file != "" {
+		fileKeys, err := ReadAPIKeys(file)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, fileKeys...)
+	}
+
+	return keys, nil
+}
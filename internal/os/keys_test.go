@@ -0,0 +1,91 @@
+package os
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mpfrancis/weather"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAPIKeys(t *testing.T) {
+	keys, err := ParseAPIKeys("abc:3, def\n# comment\n\nghi:1,")
+	assert.Nil(t, err)
+	assert.Equal(t, []weather.APIKey{{Key: "abc", Weight: 3}, {Key: "def", Weight: 1}, {Key: "ghi", Weight: 1}}, keys)
+
+	_, err = ParseAPIKeys("abc:x")
+	assert.NotNil(t, err)
+
+	_, err = ParseAPIKeys("abc:0")
+	assert.NotNil(t, err)
+}
+
+func TestGetConfigAPIKeys(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "keys")
+	if err := ioutil.WriteFile(file, []byte("ghi:2\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	env := map[string]string{
+		envBaseURL:     "url",
+		envAPIKey:      "",
+		envAPIKeys:     "abc:3,def",
+		envAPIKeysFile: file,
+		envUnits:       "",
+	}
+	for k, v := range env {
+		if err := os.Setenv(k, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer func() {
+		for k := range env {
+			os.Unsetenv(k)
+		}
+	}()
+
+	cfg, err := GetConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []weather.APIKey{{Key: "abc", Weight: 3}, {Key: "def", Weight: 1}, {Key: "ghi", Weight: 2}}, cfg.APIKeys)
+
+	// Changes to the keys file are picked up
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changed := make(chan []weather.APIKey, 1)
+	go watchConfig(ctx, Options{}, cfg, 10*time.Millisecond, nil, func(cfg *weather.Config, err error) {
+		if err == nil {
+			changed <- cfg.APIKeys
+		}
+	})
+
+	// The file is replaced atomically, a file being written could be read while it is still empty
+	time.Sleep(20 * time.Millisecond)
+	if err := ioutil.WriteFile(file+".tmp", []byte("jkl\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(file+".tmp", file); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(file, time.Now(), time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case keys := <-changed:
+		assert.Equal(t, []weather.APIKey{{Key: "abc", Weight: 3}, {Key: "def", Weight: 1}, {Key: "jkl", Weight: 1}}, keys)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Keys file change was not detected")
+	}
+}
@@ -0,0 +1,28 @@
+package os
+
+import (
+	"github.com/mpfrancis/weather"
+	"github.com/sirupsen/logrus"
+)
+
+// Log formats.
+const (
+	LogFormatJSON = "json"
+	LogFormatText = "text"
+)
+
+// ConfigureLogging applies the log level and format of the config to the standard logger.
+// Messages are written as JSON objects, one per line, unless the text format is configured.
+func ConfigureLogging(cfg *weather.Config) {
+	level, err := logrus.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		level = logrus.InfoLevel
+	}
+	logrus.SetLevel(level)
+
+	if cfg.LogFormat == LogFormatText {
+		logrus.SetFormatter(&logrus.TextFormatter{})
+	} else {
+		logrus.SetFormatter(&logrus.JSONFormatter{})
+	}
+}
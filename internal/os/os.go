@@ -2,7 +2,10 @@ package os
 
 import (
 	"errors"
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/mpfrancis/weather"
@@ -10,58 +13,383 @@ import (
 )
 
 const (
+	envProviders             = "WEATHER_PROVIDERS"
+	envProviderTimeout       = "WEATHER_PROVIDER_TIMEOUT"
+	envOpenMeteoURL          = "OPENMETEO_BASEURL"
+	envOpenMeteoGeocodingURL = "OPENMETEO_GEOCODING_BASEURL"
+
 	envBaseURL         = "WEATHER_BASEURL"
 	envAPIKey          = "WEATHER_APIKEY"
+	envAPIKeys         = "WEATHER_APIKEYS"
+	envAPIKeysFile     = "WEATHER_APIKEYS_FILE"
+	envAPIKeyCooldown  = "WEATHER_APIKEY_COOLDOWN"
+	envAPIKeyFile      = "WEATHER_APIKEY_FILE"
+	envAPIKeyCommand   = "WEATHER_APIKEY_COMMAND"
+	envAPIKeyRefresh   = "WEATHER_APIKEY_REFRESH"
 	envUnits           = "WEATHER_UNITS"
 	envAddr            = "SERVER_ADDRESS"
+	envShutdownTimeout = "SERVER_SHUTDOWN_TIMEOUT"
+	envAdminToken      = "SERVER_ADMIN_TOKEN"
+	envConfigFile      = "WEATHER_CONFIG_FILE"
+	envLocations       = "WEATHER_LOCATIONS"
+	envLogLevel        = "LOG_LEVEL"
+	envLogFormat       = "LOG_FORMAT"
 	envCacheExpiration = "CACHE_EXPIRATION"
+
+	envCacheTTLCurrent         = "CACHE_TTL_CURRENT"
+	envCacheTTLForecast        = "CACHE_TTL_FORECAST"
+	envCacheTTLFromObservation = "CACHE_TTL_FROM_OBSERVATION"
+	envCacheTTLMin             = "CACHE_TTL_MIN"
+
+	envUpstreamCallsPerMinute = "UPSTREAM_CALLS_PER_MINUTE"
+	envUpstreamCallsPerMonth  = "UPSTREAM_CALLS_PER_MONTH"
+	envUpstreamQuotaReserve   = "UPSTREAM_QUOTA_RESERVE"
+	envUpstreamUsageFile      = "UPSTREAM_USAGE_FILE"
+	envPrefetchTopN           = "PREFETCH_TOP_N"
+	envPrefetchLead           = "PREFETCH_LEAD"
+	envPrefetchShare          = "PREFETCH_SHARE"
 )
 
 var (
-	errMissingBaseURL = errors.New("WEATHER_BASEURL environment variable is required")
-	errMissingAPIKey  = errors.New("WEATHER_APIKEY environment variable is required")
-	errInvalidUnits   = errors.New("Invalid units, use: standard, metric, imperial. Default: metric")
+	errMissingBaseURL   = errors.New("WEATHER_BASEURL, --openweather-base-url or openweather.base_url is required")
+	errMissingAPIKey    = errors.New("WEATHER_APIKEY, WEATHER_APIKEY_FILE, WEATHER_APIKEY_COMMAND, WEATHER_APIKEYS or WEATHER_APIKEYS_FILE, or the matching flag or config file setting, is required")
+	errAPIKeySources    = errors.New("Only one of WEATHER_APIKEY, WEATHER_APIKEY_FILE and WEATHER_APIKEY_COMMAND, or the matching flags or config file settings, may be set")
+	errInvalidUnits     = errors.New("Invalid units, use: standard, metric, imperial. Default: metric")
+	errInvalidLogLevel  = errors.New("Invalid log level, use: panic, fatal, error, warn, info, debug, trace. Default: info")
+	errInvalidLogFormat = errors.New("Invalid log format, use: json, text. Default: json")
+	errInvalidProvider  = errors.New("Invalid provider, use a comma separated list of: openweather, openmeteo. Default: openweather")
 )
 
-// GetConfig gets configuration environment variables and returns them in a config object.
-// Environment variables WEATHER_BASEURL and WEATHER_APIKEY are required to be set.
+// Errors are the problems found in a configuration, every invalid setting is reported.
+type Errors []error
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i := range e {
+		msgs[i] = e[i].Error()
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+// Is reports whether any of the errors matches the target.
+func (e Errors) Is(target error) bool {
+	for i := range e {
+		if errors.Is(e[i], target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetConfig gets the configuration from the environment and the config file named by WEATHER_CONFIG_FILE.
+// See Load.
 func GetConfig() (*weather.Config, error) {
-	var cfg weather.Config
+	return Load(Options{})
+}
 
-	cfg.BaseURL = os.Getenv(envBaseURL)
-	cfg.APIKey = os.Getenv(envAPIKey)
-	cfg.Units = weather.Unit(os.Getenv(envUnits))
-	cfg.ServerAddress = os.Getenv(envAddr)
-	cfg.CacheExpiration = os.Getenv(envCacheExpiration)
+// Load gets the configuration from the command-line flags, the environment and the config file, in this order of precedence,
+// settings given by none of them take their defaults.
+// Environment variable WEATHER_PROVIDERS lists the providers in order of preference.
+// When open weather is one of them, WEATHER_BASEURL is required to be set, as is at least one API key
+// from WEATHER_APIKEY, WEATHER_APIKEYS or the file named by WEATHER_APIKEYS_FILE.
+// The single API key may instead be read from a secret source: the file named by WEATHER_APIKEY_FILE,
+// the output of WEATHER_APIKEY_COMMAND or, when neither is set, the APIKeySource of the options.
+// Cache TTLs for current conditions and forecasts default to CACHE_EXPIRATION.
+// Every missing or invalid setting is reported in the returned Errors.
+func Load(opts Options) (*weather.Config, error) {
+	l := loader{flags: opts.Flags, secret: opts.APIKeySource}
+	if l.fileName = opts.File; l.fileName == "" {
+		l.fileName = os.Getenv(envConfigFile)
+	}
 
-	if cfg.BaseURL == "" {
-		return nil, errMissingBaseURL
+	if l.fileName != "" {
+		var errs []error
+		l.file, errs = readConfigFile(l.fileName)
+		l.errs = append(l.errs, errs...)
 	}
 
-	if cfg.APIKey == "" {
-		return nil, errMissingAPIKey
+	cfg := l.config()
+	if len(l.errs) > 0 {
+		return nil, l.errs
+	}
+
+	return cfg, nil
+}
+
+// ServerAddress returns the address the server listens on, SERVER_ADDRESS or :10000 by default.
+func ServerAddress() string {
+	return getString(envAddr, ":10000")
+}
+
+// AdminToken returns the bearer token of the server's /admin endpoints, SERVER_ADMIN_TOKEN.
+func AdminToken() string {
+	return os.Getenv(envAdminToken)
+}
+
+// loader reads the configuration from its sources and collects the settings that are missing or invalid.
+type loader struct {
+	flags    map[string]string
+	file     map[string]string
+	fileName string
+	secret   SecretSource
+	errs     Errors
+}
+
+// config reads the configuration, the settings that are missing or invalid are collected in errs.
+func (l *loader) config() *weather.Config {
+	var cfg weather.Config
+
+	cfg.BaseURL = l.string("openweather.base_url", "")
+	cfg.APIKey = l.apiKey(&cfg)
+	cfg.Units = weather.Unit(strings.ToLower(l.string("units", string(weather.Metric))))
+	cfg.ServerAddress = l.string("server.address", ":10000")
+	cfg.ShutdownTimeout = l.duration("server.shutdown_timeout", 20*time.Second)
+	if cfg.AdminToken = l.string("server.admin_token", ""); cfg.AdminToken != "" {
+		weather.RegisterSecret(cfg.AdminToken)
 	}
+	cfg.CacheExpiration, _ = l.lookup("cache.expiration")
+	cfg.APIKeysFile = l.string("openweather.api_keys_file", "")
+	cfg.Providers = strings.Split(l.string("providers", weather.OpenWeatherProvider), ",")
+	cfg.ProviderTimeout = l.duration("provider_timeout", 5*time.Second)
+	cfg.OpenMeteoURL = l.string("openmeteo.base_url", "https://api.open-meteo.com/v1")
+	cfg.OpenMeteoGeocodingURL = l.string("openmeteo.geocoding_base_url", "https://geocoding-api.open-meteo.com/v1")
 
-	switch cfg.Units {
-	case "standard", "metric", "imperial":
-	case "":
-		cfg.Units = "metric"
-	default:
-		return nil, errInvalidUnits
+	for i := range cfg.Providers {
+		cfg.Providers[i] = strings.ToLower(strings.TrimSpace(cfg.Providers[i]))
+		switch cfg.Providers[i] {
+		case weather.OpenWeatherProvider, weather.OpenMeteoProvider:
+		default:
+			l.errs = append(l.errs, errInvalidProvider)
+		}
 	}
 
-	if cfg.ServerAddress == "" {
-		cfg.ServerAddress = ":10000"
+	if cfg.BaseURL == "" && cfg.UsesProvider(weather.OpenWeatherProvider) {
+		l.errs = append(l.errs, errMissingBaseURL)
 	}
 
 	var err error
-	cfg.CacheExpirationDur, err = time.ParseDuration(cfg.CacheExpiration)
+	list, _ := l.lookup("openweather.api_keys")
+	if cfg.APIKeys, err = getAPIKeys(cfg.APIKey, list, cfg.APIKeysFile); err != nil {
+		l.errs = append(l.errs, err)
+	} else if len(cfg.APIKeys) == 0 && cfg.UsesProvider(weather.OpenWeatherProvider) {
+		l.errs = append(l.errs, errMissingAPIKey)
+	}
+
+	cfg.APIKeyCooldown = l.duration("openweather.api_key_cooldown", 5*time.Minute)
+
+	if !cfg.Units.Valid() {
+		l.errs = append(l.errs, errInvalidUnits)
+	}
+
+	cfg.CacheExpirationDur = l.duration("cache.expiration", 2*time.Minute)
+
+	cfg.CacheTTLs = map[weather.DataType]weather.TTLPolicy{
+		weather.CurrentData: {
+			TTL:             l.duration("cache.ttl.current", cfg.CacheExpirationDur),
+			FromObservation: l.bool("cache.ttl.from_observation", false),
+			MinTTL:          l.duration("cache.ttl.min", 0),
+		},
+		weather.ForecastData: {
+			TTL: l.duration("cache.ttl.forecast", cfg.CacheExpirationDur),
+		},
+	}
+
+	cfg.UpstreamCallsPerMinute = l.int("upstream.calls_per_minute", 0)
+	cfg.UpstreamCallsPerMonth = l.int("upstream.calls_per_month", 0)
+	cfg.UpstreamQuotaReserve = l.float("upstream.quota_reserve", 0.1)
+	cfg.UpstreamUsageFile = l.string("upstream.usage_file", "")
+	cfg.PrefetchTopN = l.int("prefetch.top_n", 0)
+	cfg.PrefetchLead = l.duration("prefetch.lead", 30*time.Second)
+	cfg.PrefetchShare = l.float("prefetch.share", 0.2)
+	cfg.Locations = l.locations("locations")
+
+	cfg.LogLevel = strings.ToLower(l.string("log.level", logrus.InfoLevel.String()))
+	if _, err := logrus.ParseLevel(cfg.LogLevel); err != nil {
+		l.errs = append(l.errs, errInvalidLogLevel)
+	}
+
+	cfg.LogFormat = strings.ToLower(l.string("log.format", LogFormatJSON))
+	if cfg.LogFormat != LogFormatJSON && cfg.LogFormat != LogFormatText {
+		l.errs = append(l.errs, errInvalidLogFormat)
+	}
+
+	return &cfg
+}
+
+// apiKey returns the single open weather API key, given directly or read from its secret source.
+// The secret source settings and the refresh interval are recorded in the config.
+func (l *loader) apiKey(cfg *weather.Config) string {
+	key := l.string("openweather.api_key", "")
+	cfg.APIKeyFile = l.string("openweather.api_key_file", "")
+	cfg.APIKeyCommand = l.string("openweather.api_key_command", "")
+	cfg.APIKeyRefresh = l.duration("openweather.api_key_refresh", 5*time.Minute)
+
+	set := 0
+	for _, value := range []string{key, cfg.APIKeyFile, cfg.APIKeyCommand} {
+		if value != "" {
+			set++
+		}
+	}
+
+	source := l.secret
+	switch {
+	case set > 1:
+		l.errs = append(l.errs, errAPIKeySources)
+		return ""
+	case key != "":
+		return key
+	case cfg.APIKeyFile != "":
+		source = SecretFile(cfg.APIKeyFile)
+	case cfg.APIKeyCommand != "":
+		source = SecretCommand(strings.Fields(cfg.APIKeyCommand))
+	case source == nil:
+		return ""
+	}
+
+	key, err := readSecret(source)
 	if err != nil {
-		if cfg.CacheExpiration != "" {
-			logrus.Warn("Unable to parse cache expiration, defaulting to two minutes")
+		l.errs = append(l.errs, fmt.Errorf("Unable to read the API key: %w", err))
+		return ""
+	}
+
+	return key
+}
+
+// lookup returns the value of the setting from the source with the highest precedence, along with a description of the source.
+func (l *loader) lookup(key string) (value, source string) {
+	if value, ok := l.flags[key]; ok {
+		return value, "flag --" + flagName(key)
+	}
+
+	s, _ := findSetting(key)
+	if value := os.Getenv(s.env); value != "" {
+		return value, s.env
+	}
+
+	if value, ok := l.file[key]; ok {
+		return value, key + " in " + l.fileName
+	}
+
+	return "", ""
+}
+
+// invalid records that the value of a setting is invalid.
+func (l *loader) invalid(source, value, hint string) {
+	l.errs = append(l.errs, fmt.Errorf("%s %q is invalid, %s", source, value, hint))
+}
+
+// string returns the value of the setting, or the default when it is not set.
+func (l *loader) string(key string, def string) string {
+	if value, _ := l.lookup(key); value != "" {
+		return value
+	}
+
+	return def
+}
+
+// getString returns the value of the given environment variable, or the default when it is not set.
+func getString(env string, def string) string {
+	if value := os.Getenv(env); value != "" {
+		return value
+	}
+
+	return def
+}
+
+// duration parses the duration of the setting, the default is returned when it is not set or invalid.
+func (l *loader) duration(key string, def time.Duration) time.Duration {
+	value, source := l.lookup(key)
+	if value == "" {
+		return def
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		l.invalid(source, value, "please provide a duration such as 90s or 5m")
+		return def
+	}
+
+	return d
+}
+
+// int parses the non-negative integer of the setting, the default is returned when it is not set or invalid.
+func (l *loader) int(key string, def int) int {
+	value, source := l.lookup(key)
+	if value == "" {
+		return def
+	}
+
+	i, err := strconv.Atoi(value)
+	if err != nil || i < 0 {
+		l.invalid(source, value, "please provide a whole number of zero or more")
+		return def
+	}
+
+	return i
+}
+
+// float parses the fraction between zero and one of the setting, the default is returned when it is not set or invalid.
+func (l *loader) float(key string, def float64) float64 {
+	value, source := l.lookup(key)
+	if value == "" {
+		return def
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f < 0 || 1 < f {
+		l.invalid(source, value, "please provide a number between 0 and 1")
+		return def
+	}
+
+	return f
+}
+
+// bool parses the boolean of the setting, the default is returned when it is not set or invalid.
+func (l *loader) bool(key string, def bool) bool {
+	value, source := l.lookup(key)
+	if value == "" {
+		return def
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		l.invalid(source, value, "use: true, false")
+		return def
+	}
+
+	return b
+}
+
+// locations parses the named locations of the setting, given as name=location pairs separated by semicolons.
+// Names are lower cased, locations are parsed with weather.ParseLocation.
+func (l *loader) locations(key string) map[string]weather.Location {
+	value, source := l.lookup(key)
+	if value == "" {
+		return nil
+	}
+
+	locations := make(map[string]weather.Location)
+	for _, pair := range strings.Split(value, ";") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+
+		i := strings.Index(pair, "=")
+		if i < 0 {
+			l.invalid(source, pair, "please provide name=city,country or name=lat,lon pairs separated by semicolons")
+			continue
+		}
+
+		name := strings.ToLower(strings.TrimSpace(pair[:i]))
+		loc, err := weather.ParseLocation(pair[i+1:])
+		if err != nil || name == "" {
+			l.invalid(source, pair, "please provide name=city,country or name=lat,lon pairs separated by semicolons")
+			continue
 		}
-		cfg.CacheExpirationDur = 2 * time.Minute
+		locations[name] = loc
 	}
 
-	return &cfg, nil
+	return locations
 }
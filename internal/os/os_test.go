@@ -23,13 +23,19 @@ type Case struct {
 
 func TestGetConfig(t *testing.T) {
 	cases := []Case{
-		{"Success", "url", "key", "imperial", ":11000", "5m", nil, &weather.Config{BaseURL: "url", APIKey: "key", Units: "imperial", ServerAddress: ":11000", CacheExpiration: "5m", CacheExpirationDur: 5 * time.Minute}},
-		{"Defaults", "url", "key", "", "", "", nil, &weather.Config{BaseURL: "url", APIKey: "key", Units: "metric", ServerAddress: ":10000", CacheExpirationDur: 2 * time.Minute}},
+		{"Success", "url", "key", "imperial", ":11000", "5m", nil, &weather.Config{Providers: []string{"openweather"}, ProviderTimeout: 5 * time.Second, OpenMeteoURL: "https://api.open-meteo.com/v1", OpenMeteoGeocodingURL: "https://geocoding-api.open-meteo.com/v1", BaseURL: "url", APIKey: "key", APIKeys: []weather.APIKey{{Key: "key", Weight: 1}}, APIKeyCooldown: 5 * time.Minute, Units: "imperial", APIKeyRefresh: 5 * time.Minute, LogLevel: "info", LogFormat: "json", ServerAddress: ":11000", ShutdownTimeout: 20 * time.Second, CacheExpiration: "5m", CacheExpirationDur: 5 * time.Minute, CacheTTLs: defaultTTLs(5 * time.Minute), UpstreamQuotaReserve: 0.1, PrefetchLead: 30 * time.Second, PrefetchShare: 0.2}},
+		{"Defaults", "url", "key", "", "", "", nil, &weather.Config{Providers: []string{"openweather"}, ProviderTimeout: 5 * time.Second, OpenMeteoURL: "https://api.open-meteo.com/v1", OpenMeteoGeocodingURL: "https://geocoding-api.open-meteo.com/v1", BaseURL: "url", APIKey: "key", APIKeys: []weather.APIKey{{Key: "key", Weight: 1}}, APIKeyCooldown: 5 * time.Minute, Units: "metric", APIKeyRefresh: 5 * time.Minute, LogLevel: "info", LogFormat: "json", ServerAddress: ":10000", ShutdownTimeout: 20 * time.Second, CacheExpirationDur: 2 * time.Minute, CacheTTLs: defaultTTLs(2 * time.Minute), UpstreamQuotaReserve: 0.1, PrefetchLead: 30 * time.Second, PrefetchShare: 0.2}},
 		{"Missing URL", "", "key", "", "", "", errMissingBaseURL, nil},
 		{"Missing API Key", "url", "", "", "", "", errMissingAPIKey, nil},
 		{"Invalid Units", "url", "key", "abc", "", "", errInvalidUnits, nil},
 	}
 
+	defer func() {
+		for _, env := range []string{envBaseURL, envAPIKey, envUnits, envAddr, envCacheExpiration} {
+			os.Unsetenv(env)
+		}
+	}()
+
 	for i := range cases {
 		if err := os.Setenv(envBaseURL, cases[i].baseURL); err != nil {
 			t.Fatal(err)
@@ -55,3 +61,140 @@ func TestGetConfig(t *testing.T) {
 		assert.Equal(t, cfg, cases[i].expectedConfig)
 	}
 }
+
+func TestGetConfigCacheTTLs(t *testing.T) {
+	env := map[string]string{
+		envBaseURL:                 "url",
+		envAPIKey:                  "key",
+		envUnits:                   "",
+		envAddr:                    "",
+		envCacheExpiration:         "",
+		envCacheTTLCurrent:         "10m",
+		envCacheTTLForecast:        "3h",
+		envCacheTTLFromObservation: "true",
+		envCacheTTLMin:             "30s",
+	}
+	for k, v := range env {
+		if err := os.Setenv(k, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer func() {
+		for k := range env {
+			os.Unsetenv(k)
+		}
+	}()
+
+	cfg, err := GetConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, weather.TTLPolicy{TTL: 10 * time.Minute, FromObservation: true, MinTTL: 30 * time.Second}, cfg.CachePolicy(weather.CurrentData))
+	assert.Equal(t, weather.TTLPolicy{TTL: 3 * time.Hour}, cfg.CachePolicy(weather.ForecastData))
+}
+
+// defaultTTLs returns the cache policies used when only CACHE_EXPIRATION is configured.
+func defaultTTLs(d time.Duration) map[weather.DataType]weather.TTLPolicy {
+	return map[weather.DataType]weather.TTLPolicy{
+		weather.CurrentData:  {TTL: d},
+		weather.ForecastData: {TTL: d},
+	}
+}
+
+func TestGetConfigOpenMeteo(t *testing.T) {
+	env := map[string]string{
+		envProviders: "OpenMeteo, openweather",
+		envBaseURL:   "",
+		envAPIKey:    "",
+		envUnits:     "",
+	}
+	for k, v := range env {
+		if err := os.Setenv(k, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer func() {
+		for k := range env {
+			os.Unsetenv(k)
+		}
+	}()
+
+	// Open weather needs a base URL and an API key, even when it is not the primary provider
+	_, err := GetConfig()
+	assert.Equal(t, Errors{errMissingBaseURL, errMissingAPIKey}, err)
+
+	// Open-meteo needs neither
+	if err := os.Setenv(envProviders, "openmeteo"); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := GetConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{weather.OpenMeteoProvider}, cfg.Providers)
+	assert.Equal(t, "https://api.open-meteo.com/v1", cfg.OpenMeteoURL)
+
+	if err := os.Setenv(envProviders, "openmeteo,acme"); err != nil {
+		t.Fatal(err)
+	}
+	_, err = GetConfig()
+	assert.Equal(t, Errors{errInvalidProvider}, err)
+}
+
+func TestLoadErrors(t *testing.T) {
+	env := map[string]string{
+		envBaseURL:                "",
+		envAPIKey:                 "",
+		envUnits:                  "kelvin",
+		envAddr:                   "",
+		envCacheExpiration:        "5 minutes",
+		envUpstreamCallsPerMinute: "-1",
+		envLocations:              "home=Bogota,CO;work",
+		envLogFormat:              "logfmt",
+	}
+	for k, v := range env {
+		if err := os.Setenv(k, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer func() {
+		for k := range env {
+			os.Unsetenv(k)
+		}
+	}()
+
+	// Every missing or invalid setting is reported
+	cfg, err := Load(Options{Flags: map[string]string{"prefetch.share": "2"}})
+	assert.Nil(t, cfg)
+	assert.Equal(t, Errors{
+		errMissingBaseURL,
+		errMissingAPIKey,
+		errInvalidUnits,
+		errors.New(`CACHE_EXPIRATION "5 minutes" is invalid, please provide a duration such as 90s or 5m`),
+		errors.New(`UPSTREAM_CALLS_PER_MINUTE "-1" is invalid, please provide a whole number of zero or more`),
+		errors.New(`flag --prefetch-share "2" is invalid, please provide a number between 0 and 1`),
+		errors.New(`WEATHER_LOCATIONS "work" is invalid, please provide name=city,country or name=lat,lon pairs separated by semicolons`),
+		errInvalidLogFormat,
+	}, err)
+	assert.True(t, errors.Is(err, errMissingAPIKey))
+}
+
+func TestSettings(t *testing.T) {
+	cfg := weather.Config{
+		APIKeys:   []weather.APIKey{{Key: "0123456789abcdef", Weight: 2}, {Key: "short", Weight: 1}},
+		Locations: map[string]weather.Location{"work": {Coord: &weather.Coord{Lat: 4.61, Lon: -74.08}}, "home": {City: "bogota", Country: "CO"}},
+	}
+
+	settings := Settings(&cfg)
+	assert.Contains(t, settings, Setting{envAPIKeys, "****cdef:2,****:1"})
+	assert.Contains(t, settings, Setting{envLocations, "home=bogota,CO;work=4.61,-74.08"})
+	assert.Contains(t, settings, Setting{envCacheExpiration, "0s"})
+	assert.Contains(t, settings, Setting{envAdminToken, ""})
+
+	cfg.AdminToken = "admin-secret"
+	cfg.APIKeyCommand = "vault read -field=key --token s3cr3t secret/openweather"
+	settings = Settings(&cfg)
+	assert.Contains(t, settings, Setting{envAdminToken, "****"})
+	assert.Contains(t, settings, Setting{envAPIKeyCommand, "vault ****"})
+}
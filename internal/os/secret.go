@@ -0,0 +1,86 @@
+package os
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// secretTimeout bounds the time taken to read a secret.
+const secretTimeout = 10 * time.Second
+
+// SecretSource provides a secret such as an API key.
+// Secrets are read on every load of the configuration, a rotated secret is picked up on the next load.
+type SecretSource interface {
+	Secret(ctx context.Context) (string, error)
+}
+
+// SecretFunc is a function used as a secret source, e.g. a client of a secrets agent.
+type SecretFunc func(ctx context.Context) (string, error)
+
+// Secret calls the function.
+func (f SecretFunc) Secret(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// SecretFile is the path of a file holding a secret, such as a secret mounted by the platform.
+// Leading and trailing white space is trimmed.
+type SecretFile string
+
+// Secret reads the file.
+func (f SecretFile) Secret(ctx context.Context) (string, error) {
+	b, err := ioutil.ReadFile(string(f))
+	if err != nil {
+		return "", err
+	}
+
+	secret := strings.TrimSpace(string(b))
+	if secret == "" {
+		return "", fmt.Errorf("%s is empty", f)
+	}
+
+	return secret, nil
+}
+
+// SecretCommand is a command printing a secret, followed by its arguments. The command is not run by a shell.
+// Leading and trailing white space of the output is trimmed.
+type SecretCommand []string
+
+// Secret runs the command.
+func (c SecretCommand) Secret(ctx context.Context) (string, error) {
+	if len(c) == 0 {
+		return "", errors.New("Secret command is empty")
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c[0], c[1:]...)
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s failed: %w: %s", c[0], err, msg)
+		}
+		return "", fmt.Errorf("%s failed: %w", c[0], err)
+	}
+
+	secret := strings.TrimSpace(string(out))
+	if secret == "" {
+		return "", fmt.Errorf("%s printed no secret", c[0])
+	}
+
+	return secret, nil
+}
+
+// readSecret reads the secret of the source within secretTimeout.
+func readSecret(source SecretSource) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), secretTimeout)
+	defer cancel()
+
+	return source.Secret(ctx)
+}
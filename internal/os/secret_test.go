@@ -0,0 +1,103 @@
+package os
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mpfrancis/weather"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecretSources(t *testing.T) {
+	ctx := context.Background()
+
+	secret, err := SecretFile(writeFile(t, "apikey", "  abc123\n")).Secret(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, "abc123", secret)
+
+	path := writeFile(t, "apikey", "\n")
+	_, err = SecretFile(path).Secret(ctx)
+	assert.Equal(t, path+" is empty", err.Error())
+
+	secret, err = SecretCommand{"echo", "def456"}.Secret(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, "def456", secret)
+
+	_, err = SecretCommand{"sh", "-c", "echo agent unavailable >&2; exit 3"}.Secret(ctx)
+	assert.Equal(t, "sh failed: exit status 3: agent unavailable", err.Error())
+
+	_, err = SecretCommand{"true"}.Secret(ctx)
+	assert.Equal(t, "true printed no secret", err.Error())
+}
+
+func TestLoadAPIKeySecret(t *testing.T) {
+	flags := map[string]string{"openweather.base_url": "url"}
+	source := SecretFunc(func(ctx context.Context) (string, error) { return "ghi789", nil })
+
+	// The secret source settings take precedence over the source of the options
+	os.Setenv(envAPIKeyFile, writeFile(t, "apikey", "abc123\n"))
+	defer os.Unsetenv(envAPIKeyFile)
+	cfg, err := Load(Options{Flags: flags, APIKeySource: source})
+	assert.Nil(t, err)
+	assert.Equal(t, []weather.APIKey{{Key: "abc123", Weight: 1}}, cfg.APIKeys)
+	assert.Equal(t, 5*time.Minute, cfg.APIKeyRefresh)
+
+	os.Unsetenv(envAPIKeyFile)
+	cfg, err = Load(Options{Flags: flags, APIKeySource: source})
+	assert.Nil(t, err)
+	assert.Equal(t, "ghi789", cfg.APIKey)
+
+	cfg, err = Load(Options{Flags: map[string]string{"openweather.base_url": "url", "openweather.api_key_command": "echo def456"}})
+	assert.Nil(t, err)
+	assert.Equal(t, "def456", cfg.APIKey)
+	assert.Equal(t, "echo def456", cfg.APIKeyCommand)
+
+	_, err = Load(Options{Flags: map[string]string{"openweather.base_url": "url", "openweather.api_key": "abc", "openweather.api_key_command": "echo def456"}})
+	assert.Equal(t, Errors{errAPIKeySources, errMissingAPIKey}, err)
+
+	failing := SecretFunc(func(ctx context.Context) (string, error) { return "", errors.New("agent unavailable") })
+	_, err = Load(Options{Flags: flags, APIKeySource: failing})
+	assert.Equal(t, "Unable to read the API key: agent unavailable; "+errMissingAPIKey.Error(), err.Error())
+}
+
+func TestWatchAPIKeySecret(t *testing.T) {
+	var key atomic.Value
+	key.Store("abc123")
+	opts := Options{
+		Flags:        map[string]string{"openweather.base_url": "url", "openweather.api_key_refresh": "10ms"},
+		APIKeySource: SecretFunc(func(ctx context.Context) (string, error) { return key.Load().(string), nil }),
+	}
+	cfg, err := Load(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reloaded := make(chan *weather.Config, 1)
+	go watchConfig(ctx, opts, cfg, time.Minute, nil, func(cfg *weather.Config, err error) {
+		if err == nil {
+			reloaded <- cfg
+		}
+	})
+
+	// A rotated secret is picked up on the next refresh
+	key.Store("def456")
+	select {
+	case next := <-reloaded:
+		assert.Equal(t, []weather.APIKey{{Key: "def456", Weight: 1}}, next.Keys())
+	case <-time.After(5 * time.Second):
+		t.Fatal("Rotated API key was not picked up")
+	}
+
+	// The configuration is not reloaded while the secret is unchanged
+	select {
+	case <-reloaded:
+		t.Fatal("Unchanged configuration was reloaded")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
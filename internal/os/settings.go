@@ -0,0 +1,163 @@
+package os
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mpfrancis/weather"
+)
+
+// setting describes a configuration setting by its key in config files and its environment variable.
+// Its command-line flag is the key with dots and underscores replaced by dashes.
+type setting struct {
+	key   string
+	env   string
+	usage string
+}
+
+// settings are every configuration setting, in the order of the README.
+var settings = []setting{
+	{"providers", envProviders, "weather providers in order of preference: openweather, openmeteo"},
+	{"provider_timeout", envProviderTimeout, "timeout of every call to a provider"},
+	{"openmeteo.base_url", envOpenMeteoURL, "base URL of the open-meteo forecast API"},
+	{"openmeteo.geocoding_base_url", envOpenMeteoGeocodingURL, "base URL of the open-meteo geocoding API"},
+	{"openweather.base_url", envBaseURL, "base URL of the open weather API"},
+	{"openweather.api_key", envAPIKey, "open weather API key"},
+	{"openweather.api_keys", envAPIKeys, "open weather API keys separated by commas, each optionally followed by a colon and a weight"},
+	{"openweather.api_keys_file", envAPIKeysFile, "file listing open weather API keys"},
+	{"openweather.api_key_cooldown", envAPIKeyCooldown, "how long a key rejected by open weather is out of service"},
+	{"openweather.api_key_file", envAPIKeyFile, "file holding the open weather API key, such as a mounted secret"},
+	{"openweather.api_key_command", envAPIKeyCommand, "command printing the open weather API key, followed by its arguments"},
+	{"openweather.api_key_refresh", envAPIKeyRefresh, "how often the API key is read again from its file or command, zero disables refreshing"},
+	{"units", envUnits, "units of the responses: standard, metric, imperial"},
+	{"server.address", envAddr, "address the server listens on"},
+	{"server.shutdown_timeout", envShutdownTimeout, "how long in-flight requests may take to complete on shutdown"},
+	{"server.admin_token", envAdminToken, "bearer token required by the /admin endpoints, they are disabled without one"},
+	{"cache.expiration", envCacheExpiration, "default cache TTL"},
+	{"cache.ttl.current", envCacheTTLCurrent, "cache TTL of current conditions"},
+	{"cache.ttl.forecast", envCacheTTLForecast, "cache TTL of forecasts"},
+	{"cache.ttl.from_observation", envCacheTTLFromObservation, "measure the TTL of current conditions from the observation time"},
+	{"cache.ttl.min", envCacheTTLMin, "minimum cache TTL of current conditions measured from the observation time"},
+	{"upstream.calls_per_minute", envUpstreamCallsPerMinute, "open weather calls allowed per minute, zero is unlimited"},
+	{"upstream.calls_per_month", envUpstreamCallsPerMonth, "open weather calls allowed per month, zero is unlimited"},
+	{"upstream.quota_reserve", envUpstreamQuotaReserve, "share of each limit reserved for essential calls"},
+	{"upstream.usage_file", envUpstreamUsageFile, "file the upstream usage is saved to"},
+	{"prefetch.top_n", envPrefetchTopN, "number of most requested locations refreshed before they expire"},
+	{"prefetch.lead", envPrefetchLead, "how long before expiry a location is refreshed"},
+	{"prefetch.share", envPrefetchShare, "share of the calls per minute that prefetching may use"},
+	{"locations", envLocations, "named locations separated by semicolons, e.g. home=Bogota,CO;office=4.61,-74.08"},
+	{"log.level", envLogLevel, "minimum level of the messages logged: error, warn, info, debug"},
+	{"log.format", envLogFormat, "format of the messages logged: json or text"},
+}
+
+// findSetting returns the setting with the given key.
+func findSetting(key string) (setting, bool) {
+	for _, s := range settings {
+		if s.key == key {
+			return s, true
+		}
+	}
+
+	return setting{}, false
+}
+
+// flagName returns the command-line flag of the setting with the given key.
+func flagName(key string) string {
+	return strings.NewReplacer(".", "-", "_", "-").Replace(key)
+}
+
+// Setting is the effective value of a configuration setting, named by its environment variable.
+type Setting struct {
+	Name  string
+	Value string
+}
+
+// Settings returns the effective value of every setting of the config, defaults included.
+// WEATHER_APIKEYS lists the keys from every source, masked so that only their last four characters are shown,
+// SERVER_ADMIN_TOKEN is masked entirely, and so are the arguments of WEATHER_APIKEY_COMMAND, which may carry credentials.
+func Settings(cfg *weather.Config) []Setting {
+	keys := make([]string, len(cfg.APIKeys))
+	for i, key := range cfg.APIKeys {
+		keys[i] = MaskKey(key.Key) + ":" + strconv.Itoa(key.Weight)
+	}
+
+	current, forecast := cfg.CachePolicy(weather.CurrentData), cfg.CachePolicy(weather.ForecastData)
+
+	return []Setting{
+		{envProviders, strings.Join(cfg.Providers, ",")},
+		{envProviderTimeout, cfg.ProviderTimeout.String()},
+		{envOpenMeteoURL, cfg.OpenMeteoURL},
+		{envOpenMeteoGeocodingURL, cfg.OpenMeteoGeocodingURL},
+		{envBaseURL, cfg.BaseURL},
+		{envAPIKeys, strings.Join(keys, ",")},
+		{envAPIKeysFile, cfg.APIKeysFile},
+		{envAPIKeyCooldown, cfg.APIKeyCooldown.String()},
+		{envAPIKeyFile, cfg.APIKeyFile},
+		{envAPIKeyCommand, maskCommand(cfg.APIKeyCommand)},
+		{envAPIKeyRefresh, cfg.APIKeyRefresh.String()},
+		{envUnits, string(cfg.Units)},
+		{envAddr, cfg.ServerAddress},
+		{envShutdownTimeout, cfg.ShutdownTimeout.String()},
+		{envAdminToken, maskSecret(cfg.AdminToken)},
+		{envCacheExpiration, cfg.CacheExpirationDur.String()},
+		{envCacheTTLCurrent, current.TTL.String()},
+		{envCacheTTLForecast, forecast.TTL.String()},
+		{envCacheTTLFromObservation, strconv.FormatBool(current.FromObservation)},
+		{envCacheTTLMin, current.MinTTL.String()},
+		{envUpstreamCallsPerMinute, strconv.Itoa(cfg.UpstreamCallsPerMinute)},
+		{envUpstreamCallsPerMonth, strconv.Itoa(cfg.UpstreamCallsPerMonth)},
+		{envUpstreamQuotaReserve, fmt.Sprint(cfg.UpstreamQuotaReserve)},
+		{envUpstreamUsageFile, cfg.UpstreamUsageFile},
+		{envPrefetchTopN, strconv.Itoa(cfg.PrefetchTopN)},
+		{envPrefetchLead, cfg.PrefetchLead.String()},
+		{envPrefetchShare, fmt.Sprint(cfg.PrefetchShare)},
+		{envLocations, formatLocations(cfg.Locations)},
+		{envLogLevel, cfg.LogLevel},
+		{envLogFormat, cfg.LogFormat},
+	}
+}
+
+// formatLocations returns the named locations in the form of WEATHER_LOCATIONS, sorted by name.
+func formatLocations(locations map[string]weather.Location) string {
+	names := make([]string, 0, len(locations))
+	for name := range locations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for i, name := range names {
+		names[i] = name + "=" + locations[name].String()
+	}
+
+	return strings.Join(names, ";")
+}
+
+// maskSecret hides a secret entirely, an empty secret is shown as such so that its absence is visible.
+func maskSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+
+	return "****"
+}
+
+// maskCommand hides the arguments of a command but shows the program it runs.
+func maskCommand(command string) string {
+	fields := strings.Fields(command)
+	if len(fields) <= 1 {
+		return command
+	}
+
+	return fields[0] + " ****"
+}
+
+// MaskKey hides an API key but for its last four characters, short keys are hidden entirely.
+func MaskKey(key string) string {
+	if len(key) <= 8 {
+		return "****"
+	}
+
+	return "****" + key[len(key)-4:]
+}
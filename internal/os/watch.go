@@ -0,0 +1,114 @@
+package os
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+	"time"
+
+	"github.com/mpfrancis/weather"
+	"github.com/sirupsen/logrus"
+)
+
+// WatchConfig reloads the configuration when the process receives SIGHUP, or when the config file or the API keys files
+// change, until the context is done. The files are checked for changes every interval.
+// When the API key comes from a secret source, it is read again every APIKeyRefresh and the configuration is reloaded
+// if it changed.
+// Valid configurations are passed to fn. Invalid ones are logged and the current configuration is kept, fn is then
+// called with the error and a nil configuration.
+func WatchConfig(ctx context.Context, opts Options, cfg *weather.Config, interval time.Duration, fn func(*weather.Config, error)) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	watchConfig(ctx, opts, cfg, interval, hup, fn)
+}
+
+// watchConfig reloads the configuration when a signal is received on hup or when its files change.
+func watchConfig(ctx context.Context, opts Options, cfg *weather.Config, interval time.Duration, hup <-chan os.Signal, fn func(*weather.Config, error)) {
+	file := opts.File
+	if file == "" {
+		file = os.Getenv(envConfigFile)
+	}
+
+	w := fileWatcher{modTimes: make(map[string]time.Time)}
+	w.changed(file, cfg.APIKeysFile, cfg.APIKeyFile)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var refresh <-chan time.Time
+	if cfg.APIKeyRefresh > 0 && (cfg.APIKeyFile != "" || cfg.APIKeyCommand != "" || opts.APIKeySource != nil) {
+		t := time.NewTicker(cfg.APIKeyRefresh)
+		defer t.Stop()
+		refresh = t.C
+	}
+
+	for {
+		refreshed := false
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			logrus.Info("Reloading the configuration on SIGHUP")
+			w.changed(file, cfg.APIKeysFile, cfg.APIKeyFile)
+		case <-ticker.C:
+			if !w.changed(file, cfg.APIKeysFile, cfg.APIKeyFile) {
+				continue
+			}
+			logrus.Info("Reloading the configuration, a file changed")
+		case <-refresh:
+			refreshed = true
+		}
+
+		next, err := Load(opts)
+		if err != nil {
+			logrus.Errorf("Keeping the current configuration, the new one is invalid: %s", err)
+			fn(nil, err)
+			continue
+		}
+
+		// The secret is read again on every refresh, the configuration is only reloaded when it changed
+		if refreshed {
+			if reflect.DeepEqual(next, cfg) {
+				continue
+			}
+			logrus.Info("Reloading the configuration, the API key changed")
+		}
+
+		cfg = next
+		w.changed(cfg.APIKeysFile, cfg.APIKeyFile)
+		fn(cfg, nil)
+	}
+}
+
+// fileWatcher detects changes to files by their modification times.
+type fileWatcher struct {
+	modTimes map[string]time.Time
+}
+
+// changed reports whether any of the files changed since it was last checked. Files seen for the first time have not changed.
+// Files that cannot be checked are logged and ignored, empty paths are skipped.
+func (w *fileWatcher) changed(paths ...string) bool {
+	changed := false
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			logrus.Warnf("Unable to check %s for changes: %s", path, err)
+			continue
+		}
+
+		if modTime, ok := w.modTimes[path]; ok && !modTime.Equal(info.ModTime()) {
+			changed = true
+		}
+		w.modTimes[path] = info.ModTime()
+	}
+
+	return changed
+}
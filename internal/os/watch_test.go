@@ -0,0 +1,74 @@
+package os
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/mpfrancis/weather"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchConfig(t *testing.T) {
+	path := writeFile(t, "weather.yaml", yamlConfig)
+	opts := Options{File: path}
+	cfg, err := Load(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	hup := make(chan os.Signal)
+	reloaded := make(chan *weather.Config, 1)
+	rejected := make(chan error, 1)
+	go watchConfig(ctx, opts, cfg, 10*time.Millisecond, hup, func(cfg *weather.Config, err error) {
+		if err != nil {
+			rejected <- err
+			return
+		}
+		reloaded <- cfg
+	})
+
+	// SIGHUP reloads the configuration even when the files did not change
+	hup <- syscall.SIGHUP
+	select {
+	case next := <-reloaded:
+		assert.Equal(t, cfg, next)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Configuration was not reloaded on SIGHUP")
+	}
+
+	// Invalid configurations are rejected and reported, the current one is kept
+	write := func(content string) {
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(yamlConfig + "log:\n  level: verbose\n")
+	hup <- syscall.SIGHUP
+	select {
+	case <-reloaded:
+		t.Fatal("Invalid configuration was applied")
+	case err := <-rejected:
+		assert.Contains(t, err.Error(), "Invalid log level")
+	case <-time.After(5 * time.Second):
+		t.Fatal("Invalid configuration was not reported")
+	}
+
+	// Changes to the config file are picked up
+	write(yamlConfig + "log:\n  level: debug\n")
+	select {
+	case next := <-reloaded:
+		assert.Equal(t, "debug", next.LogLevel)
+		assert.Equal(t, weather.Imperial, next.Units)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Config file change was not detected")
+	}
+}
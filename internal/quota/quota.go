@@ -0,0 +1,184 @@
+package quota
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/mpfrancis/weather"
+	"github.com/sirupsen/logrus"
+)
+
+// Accountant counts upstream calls within a rolling minute and the current calendar month,
+// and refuses calls that would exceed the configured limits.
+// Non-essential calls are refused once usage enters the reserved share of either limit.
+type Accountant struct {
+	minuteLimit int
+	monthLimit  int
+	reserve     float64
+	path        string
+	now         func() time.Time
+
+	mu         sync.Mutex
+	minute     []time.Time
+	month      int
+	monthStart time.Time
+}
+
+// state is the persisted form of the accountant's counters.
+type state struct {
+	Minute     []time.Time `json:"minute"`
+	Month      int         `json:"month"`
+	MonthStart time.Time   `json:"month_start"`
+}
+
+// New returns an accountant for the limits in the config.
+// When a usage file is configured the counters are restored from it, a missing or unreadable file starts from zero.
+func New(cfg *weather.Config) *Accountant {
+	a := &Accountant{
+		minuteLimit: cfg.UpstreamCallsPerMinute,
+		monthLimit:  cfg.UpstreamCallsPerMonth,
+		reserve:     cfg.UpstreamQuotaReserve,
+		path:        cfg.UpstreamUsageFile,
+		now:         time.Now,
+	}
+
+	if a.path == "" {
+		return a
+	}
+
+	b, err := ioutil.ReadFile(a.path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logrus.Warnf("Unable to read upstream usage file, starting from zero: %s", err)
+		}
+		return a
+	}
+
+	var s state
+	if err := json.Unmarshal(b, &s); err != nil {
+		logrus.Warnf("Unable to parse upstream usage file, starting from zero: %s", err)
+		return a
+	}
+
+	a.minute, a.month, a.monthStart = s.Minute, s.Month, s.MonthStart
+
+	return a
+}
+
+// Reserve records an upstream call of the given priority.
+// It returns weather.ErrQuotaExceeded without recording the call when the call is not allowed.
+func (a *Accountant) Reserve(p weather.Priority) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.roll()
+
+	share := 1.0
+	if p == weather.NonEssential {
+		share -= a.reserve
+	}
+
+	if exceeds(len(a.minute)+1, a.minuteLimit, share) || exceeds(a.month+1, a.monthLimit, share) {
+		return weather.ErrQuotaExceeded
+	}
+
+	a.minute = append(a.minute, a.now())
+	a.month++
+
+	return nil
+}
+
+// Usage returns the current upstream usage.
+func (a *Accountant) Usage() weather.Usage {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.roll()
+
+	return weather.Usage{
+		Minute:      len(a.minute),
+		MinuteLimit: a.minuteLimit,
+		Month:       a.month,
+		MonthLimit:  a.monthLimit,
+		MonthStart:  a.monthStart,
+	}
+}
+
+// Save writes the counters to the usage file, if one is configured.
+// The file is replaced atomically so a crash never leaves a partial file behind.
+func (a *Accountant) Save() error {
+	if a.path == "" {
+		return nil
+	}
+
+	a.mu.Lock()
+	a.roll()
+	b, err := json.Marshal(state{Minute: a.minute, Month: a.month, MonthStart: a.monthStart})
+	a.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(a.path), filepath.Base(a.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), a.path)
+}
+
+// Persist saves the counters every interval until the context is done.
+func (a *Accountant) Persist(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := a.Save(); err != nil {
+				logrus.Warnf("Unable to save upstream usage: %s", err)
+			}
+		}
+	}
+}
+
+// roll drops calls older than a minute and resets the monthly counter when a new month starts.
+// The caller must hold the lock.
+func (a *Accountant) roll() {
+	now := a.now()
+
+	i := 0
+	for i < len(a.minute) && now.Sub(a.minute[i]) >= time.Minute {
+		i++
+	}
+	a.minute = a.minute[i:]
+
+	year, month, _ := now.UTC().Date()
+	monthStart := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	if !a.monthStart.Equal(monthStart) {
+		a.month = 0
+		a.monthStart = monthStart
+	}
+}
+
+// exceeds reports whether usage is above the given share of the limit, a zero limit is never exceeded.
+func exceeds(usage, limit int, share float64) bool {
+	return limit > 0 && float64(usage) > share*float64(limit)
+}
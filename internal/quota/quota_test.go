@@ -0,0 +1,77 @@
+package quota
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mpfrancis/weather"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReserve(t *testing.T) {
+	now := time.Date(2020, 12, 31, 23, 59, 0, 0, time.UTC)
+	a := New(&weather.Config{UpstreamCallsPerMinute: 10, UpstreamCallsPerMonth: 100, UpstreamQuotaReserve: 0.2})
+	a.now = func() time.Time { return now }
+
+	// Non-essential calls are shed once the reserved share of the minute limit is reached
+	for i := 0; i < 8; i++ {
+		assert.Nil(t, a.Reserve(weather.NonEssential))
+	}
+	assert.Equal(t, weather.ErrQuotaExceeded, a.Reserve(weather.NonEssential))
+
+	// Essential calls may use the reserve up to the limit
+	assert.Nil(t, a.Reserve(weather.Essential))
+	assert.Nil(t, a.Reserve(weather.Essential))
+	assert.Equal(t, weather.ErrQuotaExceeded, a.Reserve(weather.Essential))
+	assert.Equal(t, weather.Usage{Minute: 10, MinuteLimit: 10, Month: 10, MonthLimit: 100, MonthStart: time.Date(2020, 12, 1, 0, 0, 0, 0, time.UTC)}, a.Usage())
+
+	// The minute rolls, a new month resets the monthly count
+	now = now.Add(time.Minute)
+	assert.Nil(t, a.Reserve(weather.Essential))
+	assert.Equal(t, weather.Usage{Minute: 1, MinuteLimit: 10, Month: 1, MonthLimit: 100, MonthStart: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)}, a.Usage())
+}
+
+func TestReserveMonthLimit(t *testing.T) {
+	now := time.Date(2020, 12, 1, 0, 0, 0, 0, time.UTC)
+	a := New(&weather.Config{UpstreamCallsPerMonth: 3})
+	a.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, a.Reserve(weather.Essential))
+		now = now.Add(time.Hour)
+	}
+	assert.Equal(t, weather.ErrQuotaExceeded, a.Reserve(weather.Essential))
+}
+
+func TestPersistence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "quota")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Date(2020, 12, 17, 17, 0, 0, 0, time.UTC)
+	cfg := weather.Config{UpstreamCallsPerMinute: 10, UpstreamCallsPerMonth: 100, UpstreamUsageFile: filepath.Join(dir, "usage.json")}
+
+	a := New(&cfg)
+	a.now = func() time.Time { return now }
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, a.Reserve(weather.Essential))
+	}
+	assert.Nil(t, a.Save())
+
+	// A restarted accountant continues counting
+	b := New(&cfg)
+	b.now = func() time.Time { return now.Add(30 * time.Second) }
+	assert.Equal(t, a.Usage(), b.Usage())
+	assert.Nil(t, b.Reserve(weather.Essential))
+	assert.Equal(t, 4, b.Usage().Month)
+
+	// A corrupt file starts from zero
+	assert.Nil(t, ioutil.WriteFile(cfg.UpstreamUsageFile, []byte("{"), 0600))
+	c := New(&cfg)
+	assert.Equal(t, 0, c.Usage().Month)
+}
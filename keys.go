@@ -0,0 +1,130 @@
+package weather
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrNoAPIKey is returned when every upstream API key is out of service.
+var ErrNoAPIKey = errors.New("No upstream API key is available, please try again later")
+
+// APIKey is an upstream API key along with the relative share of calls it takes.
+type APIKey struct {
+	Key    string
+	Weight int
+}
+
+// KeyRing rotates upstream calls across weighted API keys using smooth weighted round robin.
+// Keys rejected by the upstream are taken out of service temporarily.
+type KeyRing struct {
+	mu   sync.Mutex
+	keys []*ringKey
+	now  func() time.Time
+}
+
+type ringKey struct {
+	APIKey
+	current        int
+	suspendedUntil time.Time
+}
+
+// NewKeyRing returns a key ring rotating across the given keys.
+func NewKeyRing(keys []APIKey) *KeyRing {
+	r := &KeyRing{now: time.Now}
+	r.Replace(keys)
+	return r
+}
+
+// Replace swaps the keys in the ring. Keys that remain in the ring keep their suspension.
+// Keys with a weight below one are given a weight of one. Every key is registered as a secret to be redacted.
+func (r *KeyRing) Replace(keys []APIKey) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	old := make(map[string]*ringKey, len(r.keys))
+	for _, k := range r.keys {
+		old[k.Key] = k
+	}
+
+	r.keys = make([]*ringKey, 0, len(keys))
+	for _, k := range keys {
+		RegisterSecret(k.Key)
+
+		if k.Weight < 1 {
+			k.Weight = 1
+		}
+
+		rk := &ringKey{APIKey: k}
+		if o, ok := old[k.Key]; ok {
+			rk.suspendedUntil = o.suspendedUntil
+		}
+		r.keys = append(r.keys, rk)
+	}
+}
+
+// Len returns the number of keys in the ring, including suspended keys.
+func (r *KeyRing) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.keys)
+}
+
+// Available returns the number of keys in service.
+func (r *KeyRing) Available() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	available := 0
+	for _, k := range r.keys {
+		if !now.Before(k.suspendedUntil) {
+			available++
+		}
+	}
+
+	return available
+}
+
+// Next returns the key to use for the next upstream call.
+// It returns ErrNoAPIKey when every key is suspended.
+func (r *KeyRing) Next() (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	var best *ringKey
+	total := 0
+	for _, k := range r.keys {
+		if now.Before(k.suspendedUntil) {
+			continue
+		}
+
+		k.current += k.Weight
+		total += k.Weight
+		if best == nil || k.current > best.current {
+			best = k
+		}
+	}
+
+	if best == nil {
+		return "", ErrNoAPIKey
+	}
+
+	best.current -= total
+	return best.Key, nil
+}
+
+// Suspend takes the key out of service for the given duration.
+func (r *KeyRing) Suspend(key string, d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, k := range r.keys {
+		if k.Key == key {
+			k.suspendedUntil = r.now().Add(d)
+			k.current = 0
+		}
+	}
+}
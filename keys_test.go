@@ -0,0 +1,56 @@
+package weather
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyRingRotation(t *testing.T) {
+	r := NewKeyRing([]APIKey{{"a", 3}, {"b", 1}, {"c", 0}})
+
+	counts := map[string]int{}
+	for i := 0; i < 50; i++ {
+		key, err := r.Next()
+		assert.Nil(t, err)
+		counts[key]++
+	}
+
+	assert.Equal(t, map[string]int{"a": 30, "b": 10, "c": 10}, counts)
+}
+
+func TestKeyRingSuspend(t *testing.T) {
+	now := time.Date(2020, 12, 17, 17, 0, 0, 0, time.UTC)
+	r := NewKeyRing([]APIKey{{"a", 1}, {"b", 1}})
+	r.now = func() time.Time { return now }
+
+	r.Suspend("a", time.Minute)
+	for i := 0; i < 3; i++ {
+		key, err := r.Next()
+		assert.Nil(t, err)
+		assert.Equal(t, "b", key)
+	}
+
+	assert.Equal(t, 1, r.Available())
+
+	r.Suspend("b", time.Hour)
+	_, err := r.Next()
+	assert.Equal(t, ErrNoAPIKey, err)
+	assert.Equal(t, 0, r.Available())
+
+	// Suspensions survive replacing the keys, new keys are in service right away
+	r.Replace([]APIKey{{"a", 1}, {"b", 1}, {"c", 1}})
+	key, err := r.Next()
+	assert.Nil(t, err)
+	assert.Equal(t, "c", key)
+
+	now = now.Add(time.Minute)
+	seen := map[string]bool{}
+	for i := 0; i < 4; i++ {
+		key, err := r.Next()
+		assert.Nil(t, err)
+		seen[key] = true
+	}
+	assert.Equal(t, map[string]bool{"a": true, "c": true}, seen)
+}
@@ -0,0 +1,98 @@
+package weather
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidLocation is returned by ParseLocation for text that is neither "city,country" nor "lat,lon".
+var ErrInvalidLocation = errors.New("Invalid location, use: city,country or lat,lon")
+
+// Location identifies a place by its city name or postal code and ISO 3166 country code, or by its coordinates.
+// Coordinates take precedence over the zip code, which takes precedence over the city.
+type Location struct {
+	City    string `json:"city"`
+	Country string `json:"country"`
+	Zip     string `json:"zip,omitempty"`
+	Coord   *Coord `json:"coord,omitempty"`
+}
+
+// Normalize returns the canonical form of the location so that equivalent locations compare equal.
+// The city is lower cased with surrounding and repeated whitespace removed, the zip and country code are upper cased.
+func (l Location) Normalize() Location {
+	return Location{
+		City:    strings.ToLower(strings.Join(strings.Fields(l.City), " ")),
+		Country: strings.ToUpper(strings.TrimSpace(l.Country)),
+		Zip:     strings.ToUpper(strings.Join(strings.Fields(l.Zip), " ")),
+		Coord:   l.Coord,
+	}
+}
+
+// ValidCountry reports whether the country is a two letter ISO 3166 country code.
+func (l Location) ValidCountry() bool {
+	if len(l.Country) != 2 {
+		return false
+	}
+
+	for _, c := range l.Country {
+		if (c < 'a' || 'z' < c) && (c < 'A' || 'Z' < c) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ParseLocation parses a location in the "city,country" or "lat,lon" form of String, e.g. "Bogota,CO" or "4.61,-74.08".
+// The location is returned normalized.
+func ParseLocation(s string) (Location, error) {
+	i := strings.LastIndex(s, ",")
+	if i < 0 {
+		return Location{}, ErrInvalidLocation
+	}
+	first, second := strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+1:])
+
+	lat, latErr := strconv.ParseFloat(first, 64)
+	lon, lonErr := strconv.ParseFloat(second, 64)
+	if latErr == nil && lonErr == nil {
+		coord := Coord{Lat: lat, Lon: lon}
+		if coord.Validate() != nil {
+			return Location{}, ErrInvalidLocation
+		}
+
+		return Location{Coord: &coord}, nil
+	}
+
+	loc := Location{City: first, Country: second}.Normalize()
+	if loc.City == "" || !loc.ValidCountry() {
+		return Location{}, ErrInvalidLocation
+	}
+
+	return loc, nil
+}
+
+// String returns the location in the "city,country", "zip,country" or "lat,lon" form used by the open weather API.
+func (l Location) String() string {
+	switch {
+	case l.Coord != nil:
+		return fmt.Sprintf("%g,%g", l.Coord.Lat, l.Coord.Lon)
+	case l.Zip != "":
+		return l.Zip + "," + l.Country
+	}
+
+	return l.City + "," + l.Country
+}
+
+// Key returns a string identifying the location, distinct for cities, zip codes and coordinates.
+func (l Location) Key() string {
+	switch {
+	case l.Coord != nil:
+		return fmt.Sprintf("@%g,%g", l.Coord.Lat, l.Coord.Lon)
+	case l.Zip != "":
+		return "zip:" + l.Zip + "|" + l.Country
+	}
+
+	return l.City + "|" + l.Country
+}
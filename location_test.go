@@ -0,0 +1,90 @@
+package weather
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type NormalizeCase struct {
+	input    Location
+	expected Location
+}
+
+func TestNormalize(t *testing.T) {
+	cases := []NormalizeCase{
+		{Location{City: "Bogota", Country: "co"}, Location{City: "bogota", Country: "CO"}},
+		{Location{City: "bogota", Country: "CO"}, Location{City: "bogota", Country: "CO"}},
+		{Location{City: "  New   York ", Country: " us "}, Location{City: "new york", Country: "US"}},
+		{Location{City: "BOGOTÁ", Country: "Co"}, Location{City: "bogotá", Country: "CO"}},
+		{Location{Zip: " sw1a  1aa ", Country: "gb"}, Location{Zip: "SW1A 1AA", Country: "GB"}},
+	}
+
+	for i := range cases {
+		assert.Equal(t, cases[i].expected, cases[i].input.Normalize())
+	}
+}
+
+type CountryCase struct {
+	country string
+	valid   bool
+}
+
+func TestValidCountry(t *testing.T) {
+	cases := []CountryCase{
+		{"CO", true},
+		{"co", true},
+		{"", false},
+		{"C", false},
+		{"COL", false},
+		{"C1", false},
+		{"colombia", false},
+	}
+
+	for i := range cases {
+		assert.Equal(t, cases[i].valid, Location{Country: cases[i].country}.ValidCountry(), cases[i].country)
+	}
+}
+
+type LocationStringCase struct {
+	location Location
+	str      string
+	key      string
+}
+
+func TestLocationString(t *testing.T) {
+	cases := []LocationStringCase{
+		{Location{City: "bogota", Country: "CO"}, "bogota,CO", "bogota|CO"},
+		{Location{City: "london", Country: "GB", Zip: "SW1A 1AA"}, "SW1A 1AA,GB", "zip:SW1A 1AA|GB"},
+		{Location{City: "bogota", Country: "CO", Coord: &Coord{Lat: 4.61, Lon: -74.08}}, "4.61,-74.08", "@4.61,-74.08"},
+	}
+
+	for i := range cases {
+		assert.Equal(t, cases[i].str, cases[i].location.String())
+		assert.Equal(t, cases[i].key, cases[i].location.Key())
+	}
+}
+
+type ParseLocationCase struct {
+	text     string
+	expected Location
+	err      error
+}
+
+func TestParseLocation(t *testing.T) {
+	cases := []ParseLocationCase{
+		{" Bogota , co", Location{City: "bogota", Country: "CO"}, nil},
+		{"Washington, D.C.,US", Location{City: "washington, d.c.", Country: "US"}, nil},
+		{"4.61,-74.08", Location{Coord: &Coord{Lat: 4.61, Lon: -74.08}}, nil},
+		{"95,-74.08", Location{}, ErrInvalidLocation},
+		{"Bogota,Colombia", Location{}, ErrInvalidLocation},
+		{",CO", Location{}, ErrInvalidLocation},
+		{"Bogota", Location{}, ErrInvalidLocation},
+	}
+
+	for i := range cases {
+		loc, err := ParseLocation(cases[i].text)
+		assert.Equal(t, cases[i].err, err, cases[i].text)
+		assert.Equal(t, cases[i].expected, loc, cases[i].text)
+	}
+}
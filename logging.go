@@ -0,0 +1,30 @@
+package weather
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying the ID of the client request that the upstream calls made with it serve.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFrom returns the request ID carried by the context, it is empty for background work such as prefetching.
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// Logger returns an entry of the standard logger with the request ID carried by the context, if any, in its request_id field.
+func Logger(ctx context.Context) *logrus.Entry {
+	entry := logrus.NewEntry(logrus.StandardLogger())
+	if id := RequestIDFrom(ctx); id != "" {
+		entry = entry.WithField("request_id", id)
+	}
+
+	return entry
+}
@@ -0,0 +1,19 @@
+package weather
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogger(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, "", RequestIDFrom(ctx))
+	assert.Equal(t, logrus.Fields{}, Logger(ctx).Data)
+
+	ctx = WithRequestID(ctx, "abc-123")
+	assert.Equal(t, "abc-123", RequestIDFrom(ctx))
+	assert.Equal(t, logrus.Fields{"request_id": "abc-123"}, Logger(ctx).Data)
+}
@@ -0,0 +1,99 @@
+package weather
+
+import "time"
+
+// SchemaVersion is the version of the canonical model and of the JSON schema describing it.
+// It changes whenever a field is removed or changes meaning, adding fields keeps the version.
+const SchemaVersion = "v1"
+
+// Observation is the current conditions at a location, as reported by any provider.
+// Temperatures and wind speeds are in the requested units, times are in UTC and are zero when unknown.
+type Observation struct {
+	Location    Location  `json:"location"`
+	Name        string    `json:"name"`
+	Coord       Coord     `json:"coord"`
+	Units       Unit      `json:"units"`
+	Time        time.Time `json:"time"`
+	UTCOffset   int       `json:"utc_offset"`
+	Temperature float64   `json:"temperature"`
+	FeelsLike   float64   `json:"feels_like"`
+	Pressure    float64   `json:"pressure"`
+	Humidity    float64   `json:"humidity"`
+	CloudCover  float64   `json:"cloud_cover"`
+	WindSpeed   float64   `json:"wind_speed"`
+	WindDeg     float64   `json:"wind_deg"`
+	Condition   string    `json:"condition"`
+	Description string    `json:"description"`
+	Sunrise     time.Time `json:"sunrise"`
+	Sunset      time.Time `json:"sunset"`
+}
+
+// Coord holds the location coordinate data.
+type Coord struct {
+	Lon float64 `json:"lon"`
+	Lat float64 `json:"lat"`
+}
+
+// Forecast is the forecast at a location, as reported by any provider.
+type Forecast struct {
+	Coord  Coord           `json:"coord"`
+	Units  Unit            `json:"units"`
+	Hourly []HourlyPoint   `json:"hourly"`
+	Daily  []DailyForecast `json:"daily"`
+	Alerts []Alert         `json:"alerts"`
+}
+
+// HourlyPoint is the forecast for an hour starting at Time.
+// The precipitation probability is between 0 and 1, precipitation is in millimeters.
+type HourlyPoint struct {
+	Time                     time.Time `json:"time"`
+	Temperature              float64   `json:"temperature"`
+	FeelsLike                float64   `json:"feels_like"`
+	Pressure                 float64   `json:"pressure"`
+	Humidity                 float64   `json:"humidity"`
+	CloudCover               float64   `json:"cloud_cover"`
+	WindSpeed                float64   `json:"wind_speed"`
+	WindDeg                  float64   `json:"wind_deg"`
+	PrecipitationProbability float64   `json:"precipitation_probability"`
+	Precipitation            float64   `json:"precipitation"`
+	Condition                string    `json:"condition"`
+	Description              string    `json:"description"`
+}
+
+// DailyForecast is the forecast for the day that includes Time.
+// The precipitation probability is between 0 and 1, precipitation is in millimeters.
+type DailyForecast struct {
+	Time                     time.Time `json:"time"`
+	TempMin                  float64   `json:"temp_min"`
+	TempMax                  float64   `json:"temp_max"`
+	Pressure                 float64   `json:"pressure"`
+	Humidity                 float64   `json:"humidity"`
+	CloudCover               float64   `json:"cloud_cover"`
+	WindSpeed                float64   `json:"wind_speed"`
+	WindDeg                  float64   `json:"wind_deg"`
+	PrecipitationProbability float64   `json:"precipitation_probability"`
+	Precipitation            float64   `json:"precipitation"`
+	UVIndex                  float64   `json:"uv_index"`
+	Condition                string    `json:"condition"`
+	Description              string    `json:"description"`
+	Sunrise                  time.Time `json:"sunrise"`
+	Sunset                   time.Time `json:"sunset"`
+}
+
+// Alert is a weather warning issued for a location.
+type Alert struct {
+	Sender      string    `json:"sender"`
+	Event       string    `json:"event"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Description string    `json:"description"`
+}
+
+// unixTime converts an upstream timestamp in seconds to a time, a missing timestamp results in the zero time.
+func unixTime(sec int64) time.Time {
+	if sec == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(sec, 0).UTC()
+}
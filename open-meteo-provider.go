@@ -0,0 +1,352 @@
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// ErrLocationNotFound is returned when a provider does not know the requested location.
+var ErrLocationNotFound = errors.New("Location not found")
+
+// kelvinOffset converts degrees celsius to kelvin.
+const kelvinOffset = 273.15
+
+// openMeteoDaily lists the daily variables requested from open-meteo, in the order of openMeteoForecast.Daily.
+const openMeteoDaily = "weather_code,temperature_2m_max,temperature_2m_min,sunrise,sunset,precipitation_sum,precipitation_probability_max,wind_speed_10m_max,wind_direction_10m_dominant,uv_index_max"
+
+// openMeteoHourly lists the hourly variables requested from open-meteo.
+const openMeteoHourly = "temperature_2m,apparent_temperature,relative_humidity_2m,pressure_msl,cloud_cover,wind_speed_10m,wind_direction_10m,precipitation_probability,precipitation,weather_code"
+
+// openMeteoCurrent lists the current variables requested from open-meteo.
+const openMeteoCurrent = "temperature_2m,apparent_temperature,relative_humidity_2m,pressure_msl,cloud_cover,wind_speed_10m,wind_direction_10m,weather_code"
+
+// weatherCodes describes the WMO weather interpretation codes used by open-meteo.
+var weatherCodes = map[int]Weather{
+	0:  {Main: "Clear", Description: "clear sky"},
+	1:  {Main: "Clear", Description: "mainly clear"},
+	2:  {Main: "Clouds", Description: "partly cloudy"},
+	3:  {Main: "Clouds", Description: "overcast"},
+	45: {Main: "Fog", Description: "fog"},
+	48: {Main: "Fog", Description: "depositing rime fog"},
+	51: {Main: "Drizzle", Description: "light drizzle"},
+	53: {Main: "Drizzle", Description: "moderate drizzle"},
+	55: {Main: "Drizzle", Description: "dense drizzle"},
+	56: {Main: "Drizzle", Description: "light freezing drizzle"},
+	57: {Main: "Drizzle", Description: "dense freezing drizzle"},
+	61: {Main: "Rain", Description: "light rain"},
+	63: {Main: "Rain", Description: "moderate rain"},
+	65: {Main: "Rain", Description: "heavy rain"},
+	66: {Main: "Rain", Description: "light freezing rain"},
+	67: {Main: "Rain", Description: "heavy freezing rain"},
+	71: {Main: "Snow", Description: "light snow"},
+	73: {Main: "Snow", Description: "moderate snow"},
+	75: {Main: "Snow", Description: "heavy snow"},
+	77: {Main: "Snow", Description: "snow grains"},
+	80: {Main: "Rain", Description: "light rain showers"},
+	81: {Main: "Rain", Description: "moderate rain showers"},
+	82: {Main: "Rain", Description: "violent rain showers"},
+	85: {Main: "Snow", Description: "light snow showers"},
+	86: {Main: "Snow", Description: "heavy snow showers"},
+	95: {Main: "Thunderstorm", Description: "thunderstorm"},
+	96: {Main: "Thunderstorm", Description: "thunderstorm with light hail"},
+	99: {Main: "Thunderstorm", Description: "thunderstorm with heavy hail"},
+}
+
+// OpenMeteo is the provider for the free open-meteo API, https://open-meteo.com.
+// It needs no API key. Descriptions are always in english.
+type OpenMeteo struct {
+	cfg    *Config
+	client Client
+
+	mu        sync.Mutex
+	locations map[Location]openMeteoLocation
+}
+
+// openMeteoLocation is a result of the open-meteo geocoding API.
+type openMeteoLocation struct {
+	Name        string  `json:"name"`
+	Latitude    float64 `json:"latitude"`
+	Longitude   float64 `json:"longitude"`
+	CountryCode string  `json:"country_code"`
+}
+
+// openMeteoForecast is the response of the open-meteo forecast API, requested with unix timestamps.
+type openMeteoForecast struct {
+	Latitude         float64 `json:"latitude"`
+	Longitude        float64 `json:"longitude"`
+	UTCOffsetSeconds int     `json:"utc_offset_seconds"`
+	Current          struct {
+		Time                int     `json:"time"`
+		Temperature2m       float64 `json:"temperature_2m"`
+		ApparentTemperature float64 `json:"apparent_temperature"`
+		RelativeHumidity2m  float64 `json:"relative_humidity_2m"`
+		PressureMsl         float64 `json:"pressure_msl"`
+		CloudCover          float64 `json:"cloud_cover"`
+		WindSpeed10m        float64 `json:"wind_speed_10m"`
+		WindDirection10m    float64 `json:"wind_direction_10m"`
+		WeatherCode         int     `json:"weather_code"`
+	} `json:"current"`
+	Hourly struct {
+		Time                     []int     `json:"time"`
+		Temperature2m            []float64 `json:"temperature_2m"`
+		ApparentTemperature      []float64 `json:"apparent_temperature"`
+		RelativeHumidity2m       []float64 `json:"relative_humidity_2m"`
+		PressureMsl              []float64 `json:"pressure_msl"`
+		CloudCover               []float64 `json:"cloud_cover"`
+		WindSpeed10m             []float64 `json:"wind_speed_10m"`
+		WindDirection10m         []float64 `json:"wind_direction_10m"`
+		PrecipitationProbability []float64 `json:"precipitation_probability"`
+		Precipitation            []float64 `json:"precipitation"`
+		WeatherCode              []int     `json:"weather_code"`
+	} `json:"hourly"`
+	Daily struct {
+		Time                        []int     `json:"time"`
+		WeatherCode                 []int     `json:"weather_code"`
+		Temperature2mMax            []float64 `json:"temperature_2m_max"`
+		Temperature2mMin            []float64 `json:"temperature_2m_min"`
+		Sunrise                     []int     `json:"sunrise"`
+		Sunset                      []int     `json:"sunset"`
+		PrecipitationSum            []float64 `json:"precipitation_sum"`
+		PrecipitationProbabilityMax []float64 `json:"precipitation_probability_max"`
+		WindSpeed10mMax             []float64 `json:"wind_speed_10m_max"`
+		WindDirection10mDominant    []float64 `json:"wind_direction_10m_dominant"`
+		UVIndexMax                  []float64 `json:"uv_index_max"`
+	} `json:"daily"`
+}
+
+// NewOpenMeteo returns an open-meteo provider calling the APIs at cfg.OpenMeteoURL and cfg.OpenMeteoGeocodingURL.
+func NewOpenMeteo(cfg *Config, client Client) *OpenMeteo {
+	return &OpenMeteo{
+		cfg:       cfg,
+		client:    client,
+		locations: make(map[Location]openMeteoLocation),
+	}
+}
+
+// Name returns "openmeteo".
+func (o *OpenMeteo) Name() string {
+	return OpenMeteoProvider
+}
+
+// Current geocodes the location and requests its current conditions along with today's sunrise and sunset.
+func (o *OpenMeteo) Current(ctx context.Context, loc Location, opts Options) (*Observation, error) {
+	l, err := o.geocode(ctx, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := o.forecast(ctx, Coord{Lat: l.Latitude, Lon: l.Longitude}, opts, 1, false)
+	if err != nil {
+		return nil, err
+	}
+
+	c := f.Current
+	code := weatherCodes[c.WeatherCode]
+	obs := Observation{
+		Location:    Location{City: loc.City, Country: strings.ToUpper(l.CountryCode), Zip: loc.Zip, Coord: loc.Coord},
+		Name:        l.Name,
+		Coord:       Coord{Lat: l.Latitude, Lon: l.Longitude},
+		Units:       opts.Units,
+		Time:        unixTime(int64(c.Time)),
+		UTCOffset:   f.UTCOffsetSeconds,
+		Temperature: temperature(c.Temperature2m, opts.Units),
+		FeelsLike:   temperature(c.ApparentTemperature, opts.Units),
+		Pressure:    math.Round(c.PressureMsl),
+		Humidity:    math.Round(c.RelativeHumidity2m),
+		CloudCover:  math.Round(c.CloudCover),
+		WindSpeed:   c.WindSpeed10m,
+		WindDeg:     math.Round(c.WindDirection10m),
+		Condition:   code.Main,
+		Description: code.Description,
+		Sunrise:     unixTime(int64(intAt(f.Daily.Sunrise, 0))),
+		Sunset:      unixTime(int64(intAt(f.Daily.Sunset, 0))),
+	}
+
+	return &obs, nil
+}
+
+// Forecast requests a seven day hourly and daily forecast.
+func (o *OpenMeteo) Forecast(ctx context.Context, coord Coord, opts Options) (*Forecast, error) {
+	f, err := o.forecast(ctx, coord, opts, 7, true)
+	if err != nil {
+		return nil, err
+	}
+
+	h := f.Hourly
+	hourly := make([]HourlyPoint, len(h.Time))
+	for i := range h.Time {
+		code := weatherCodes[intAt(h.WeatherCode, i)]
+		hourly[i] = HourlyPoint{
+			Time:                     unixTime(int64(h.Time[i])),
+			Temperature:              temperature(floatAt(h.Temperature2m, i), opts.Units),
+			FeelsLike:                temperature(floatAt(h.ApparentTemperature, i), opts.Units),
+			Pressure:                 math.Round(floatAt(h.PressureMsl, i)),
+			Humidity:                 math.Round(floatAt(h.RelativeHumidity2m, i)),
+			CloudCover:               math.Round(floatAt(h.CloudCover, i)),
+			WindSpeed:                floatAt(h.WindSpeed10m, i),
+			WindDeg:                  math.Round(floatAt(h.WindDirection10m, i)),
+			PrecipitationProbability: floatAt(h.PrecipitationProbability, i) / 100,
+			Precipitation:            floatAt(h.Precipitation, i),
+			Condition:                code.Main,
+			Description:              code.Description,
+		}
+	}
+
+	d := f.Daily
+	daily := make([]DailyForecast, len(d.Time))
+	for i := range d.Time {
+		code := weatherCodes[intAt(d.WeatherCode, i)]
+		daily[i] = DailyForecast{
+			Time:                     unixTime(int64(d.Time[i])),
+			TempMin:                  temperature(floatAt(d.Temperature2mMin, i), opts.Units),
+			TempMax:                  temperature(floatAt(d.Temperature2mMax, i), opts.Units),
+			WindSpeed:                floatAt(d.WindSpeed10mMax, i),
+			WindDeg:                  math.Round(floatAt(d.WindDirection10mDominant, i)),
+			PrecipitationProbability: floatAt(d.PrecipitationProbabilityMax, i) / 100,
+			Precipitation:            floatAt(d.PrecipitationSum, i),
+			UVIndex:                  floatAt(d.UVIndexMax, i),
+			Condition:                code.Main,
+			Description:              code.Description,
+			Sunrise:                  unixTime(int64(intAt(d.Sunrise, i))),
+			Sunset:                   unixTime(int64(intAt(d.Sunset, i))),
+		}
+	}
+
+	return &Forecast{Coord: coord, Units: opts.Units, Hourly: hourly, Daily: daily, Alerts: []Alert{}}, nil
+}
+
+// Geocode looks the location up with the open-meteo geocoding API.
+func (o *OpenMeteo) Geocode(ctx context.Context, loc Location) (Coord, error) {
+	l, err := o.geocode(ctx, loc)
+	if err != nil {
+		return Coord{}, err
+	}
+
+	return Coord{Lat: l.Latitude, Lon: l.Longitude}, nil
+}
+
+// geocode returns the best match for the city or zip code within its country, results are remembered.
+// Locations given by their coordinates are named after them, without calling the geocoding API.
+func (o *OpenMeteo) geocode(ctx context.Context, loc Location) (openMeteoLocation, error) {
+	if loc.Coord != nil {
+		return openMeteoLocation{Name: loc.String(), Latitude: loc.Coord.Lat, Longitude: loc.Coord.Lon}, nil
+	}
+
+	o.mu.Lock()
+	l, ok := o.locations[loc]
+	o.mu.Unlock()
+	if ok {
+		return l, nil
+	}
+
+	query := url.Values{}
+	query.Set("name", loc.City)
+	if loc.Zip != "" {
+		query.Set("name", loc.Zip)
+	}
+	query.Set("count", "10")
+	query.Set("format", "json")
+
+	var results struct {
+		Results []openMeteoLocation `json:"results"`
+	}
+	if err := o.get(ctx, o.cfg.OpenMeteoGeocodingURL+"/search", query, &results); err != nil {
+		return openMeteoLocation{}, err
+	}
+
+	for _, r := range results.Results {
+		if strings.EqualFold(r.CountryCode, loc.Country) {
+			o.mu.Lock()
+			o.locations[loc] = r
+			o.mu.Unlock()
+
+			return r, nil
+		}
+	}
+
+	return openMeteoLocation{}, ErrLocationNotFound
+}
+
+// forecast calls the forecast API for the given number of days, hourly data is only requested when needed.
+// Temperatures for the standard unit type are requested in celsius and converted by the caller.
+func (o *OpenMeteo) forecast(ctx context.Context, coord Coord, opts Options, days int, hourly bool) (*openMeteoForecast, error) {
+	query := url.Values{}
+	query.Set("latitude", fmt.Sprint(coord.Lat))
+	query.Set("longitude", fmt.Sprint(coord.Lon))
+	query.Set("current", openMeteoCurrent)
+	query.Set("daily", openMeteoDaily)
+	if hourly {
+		query.Set("hourly", openMeteoHourly)
+	}
+	query.Set("forecast_days", fmt.Sprint(days))
+	query.Set("timeformat", "unixtime")
+	query.Set("timezone", "auto")
+	query.Set("wind_speed_unit", "ms")
+	if opts.Units == Imperial {
+		query.Set("temperature_unit", "fahrenheit")
+		query.Set("wind_speed_unit", "mph")
+	}
+
+	var f openMeteoForecast
+	if err := o.get(ctx, o.cfg.OpenMeteoURL+"/forecast", query, &f); err != nil {
+		return nil, err
+	}
+
+	return &f, nil
+}
+
+// get calls an open-meteo endpoint and decodes the response into v.
+func (o *OpenMeteo) get(ctx context.Context, endpoint string, query url.Values, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+
+	response, err := o.client.Do(req)
+	if err != nil {
+		return RedactError(err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		var payload struct {
+			Reason string `json:"reason"`
+		}
+		json.NewDecoder(response.Body).Decode(&payload)
+		return &UpstreamError{Provider: OpenMeteoProvider, Code: response.StatusCode, Message: payload.Reason}
+	}
+
+	return json.NewDecoder(response.Body).Decode(v)
+}
+
+// temperature converts a temperature from open-meteo, which is in celsius unless fahrenheit was requested.
+func temperature(t float64, units Unit) float64 {
+	if units == Standard {
+		return math.Round((t+kelvinOffset)*100) / 100
+	}
+
+	return t
+}
+
+func floatAt(values []float64, i int) float64 {
+	if i < len(values) {
+		return values[i]
+	}
+
+	return 0
+}
+
+func intAt(values []int, i int) int {
+	if i < len(values) {
+		return values[i]
+	}
+
+	return 0
+}
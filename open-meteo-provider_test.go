@@ -0,0 +1,134 @@
+package weather
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newOpenMeteoFake returns a local fake of the open-meteo geocoding and forecast APIs.
+func newOpenMeteoFake(t *testing.T, geocodeCalls *int) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/geocoding/search", func(w http.ResponseWriter, r *http.Request) {
+		*geocodeCalls++
+		assert.Equal(t, "Bogota", r.FormValue("name"))
+		fmt.Fprint(w, `{"results": [
+			{"name": "Bogota", "latitude": 40.87, "longitude": -74.03, "country_code": "US"},
+			{"name": "Bogotá", "latitude": 4.61, "longitude": -74.08, "country_code": "CO"}
+		]}`)
+	})
+	mux.HandleFunc("/v1/forecast", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "4.61", r.FormValue("latitude"))
+		assert.Equal(t, "-74.08", r.FormValue("longitude"))
+		if r.FormValue("temperature_unit") == "fahrenheit" {
+			assert.Equal(t, "mph", r.FormValue("wind_speed_unit"))
+			fmt.Fprint(w, `{"current": {"temperature_2m": 68}, "daily": {"time": [1608825600]}}`)
+			return
+		}
+
+		assert.Equal(t, "ms", r.FormValue("wind_speed_unit"))
+		fmt.Fprint(w, `{
+			"latitude": 4.61,
+			"longitude": -74.08,
+			"utc_offset_seconds": -18000,
+			"current": {"time": 1608843600, "temperature_2m": 20, "apparent_temperature": 19.5, "relative_humidity_2m": 37, "pressure_msl": 1025.4, "cloud_cover": 40, "wind_speed_10m": 2.6, "wind_direction_10m": 230, "weather_code": 2},
+			"hourly": {"time": [1608843600], "temperature_2m": [20], "precipitation_probability": [40], "precipitation": [0.2], "weather_code": [2]},
+			"daily": {
+				"time": [1608825600, 1608912000],
+				"weather_code": [61, 63],
+				"temperature_2m_max": [19.68, 17.74],
+				"temperature_2m_min": [8.89, 10.14],
+				"sunrise": [1608807628, 1608894056],
+				"sunset": [1608850304, 1608936733],
+				"precipitation_sum": [6.42, 12.71],
+				"precipitation_probability_max": [97, 100],
+				"wind_speed_10m_max": [0.45, 0.75],
+				"wind_direction_10m_dominant": [190, 290],
+				"uv_index_max": [11.99, 12.08]
+			}
+		}`)
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func TestOpenMeteo(t *testing.T) {
+	var geocodeCalls int
+	server := newOpenMeteoFake(t, &geocodeCalls)
+	defer server.Close()
+
+	cfg := Config{OpenMeteoURL: server.URL + "/v1", OpenMeteoGeocodingURL: server.URL + "/geocoding"}
+	provider := NewOpenMeteo(&cfg, http.DefaultClient)
+	ctx := context.Background()
+	bogota := Location{City: "Bogota", Country: "CO"}
+
+	obs, err := provider.Current(ctx, bogota, Options{Units: Metric})
+	assert.Nil(t, err)
+	assert.Equal(t, &Observation{
+		Location:    bogota,
+		Name:        "Bogotá",
+		Coord:       Coord{Lat: 4.61, Lon: -74.08},
+		Units:       Metric,
+		Time:        time.Unix(1608843600, 0).UTC(),
+		UTCOffset:   -18000,
+		Temperature: 20,
+		FeelsLike:   19.5,
+		Pressure:    1025,
+		Humidity:    37,
+		CloudCover:  40,
+		WindSpeed:   2.6,
+		WindDeg:     230,
+		Condition:   "Clouds",
+		Description: "partly cloudy",
+		Sunrise:     time.Unix(1608807628, 0).UTC(),
+		Sunset:      time.Unix(1608850304, 0).UTC(),
+	}, obs)
+
+	f, err := provider.Forecast(ctx, Coord{Lat: 4.61, Lon: -74.08}, Options{Units: Metric})
+	assert.Nil(t, err)
+	assert.Len(t, f.Daily, 2)
+	assert.Equal(t, DailyForecast{
+		Time:                     time.Unix(1608912000, 0).UTC(),
+		TempMin:                  10.14,
+		TempMax:                  17.74,
+		WindSpeed:                0.75,
+		WindDeg:                  290,
+		PrecipitationProbability: 1,
+		Precipitation:            12.71,
+		UVIndex:                  12.08,
+		Condition:                "Rain",
+		Description:              "moderate rain",
+		Sunrise:                  time.Unix(1608894056, 0).UTC(),
+		Sunset:                   time.Unix(1608936733, 0).UTC(),
+	}, f.Daily[1])
+	assert.Equal(t, []HourlyPoint{{Time: time.Unix(1608843600, 0).UTC(), Temperature: 20, PrecipitationProbability: 0.4, Precipitation: 0.2, Condition: "Clouds", Description: "partly cloudy"}}, f.Hourly)
+
+	coord, err := provider.Geocode(ctx, bogota)
+	assert.Nil(t, err)
+	assert.Equal(t, Coord{Lat: 4.61, Lon: -74.08}, coord)
+	assert.Equal(t, 1, geocodeCalls)
+
+	// Unit conversions
+	obs, err = provider.Current(ctx, bogota, Options{Units: Imperial})
+	assert.Nil(t, err)
+	assert.Equal(t, 68.0, obs.Temperature)
+
+	obs, err = provider.Current(ctx, bogota, Options{Units: Standard})
+	assert.Nil(t, err)
+	assert.Equal(t, 293.15, obs.Temperature)
+
+	// Locations outside the requested country are not matched
+	_, err = provider.Geocode(ctx, Location{City: "Bogota", Country: "MX"})
+	assert.Equal(t, ErrLocationNotFound, err)
+
+	// Locations given by their coordinates are not geocoded
+	obs, err = provider.Current(ctx, Location{Coord: &Coord{Lat: 4.61, Lon: -74.08}}, Options{Units: Metric})
+	assert.Nil(t, err)
+	assert.Equal(t, "4.61,-74.08", obs.Name)
+	assert.Equal(t, 2, geocodeCalls)
+}
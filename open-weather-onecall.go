@@ -12,6 +12,7 @@ type OneCallResponse struct {
 	Minutely       []Minutely `json:"minutely"`
 	Hourly         []Hourly   `json:"hourly"`
 	Daily          []Daily    `json:"daily"`
+	Alerts         []Alerts   `json:"alerts"`
 }
 
 // Current holds the current forecast data from
@@ -97,3 +98,12 @@ type Daily struct {
 	Rain      float64   `json:"rain"`
 	Uvi       float64   `json:"uvi"`
 }
+
+// Alerts holds national weather alerts.
+type Alerts struct {
+	SenderName  string `json:"sender_name"`
+	Event       string `json:"event"`
+	Start       int    `json:"start"`
+	End         int    `json:"end"`
+	Description string `json:"description"`
+}
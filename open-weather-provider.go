@@ -0,0 +1,225 @@
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// OpenWeather is the provider for the open weather API, https://openweathermap.org/api.
+type OpenWeather struct {
+	cfg    *Config
+	client Client
+	keys   *KeyRing
+	quota  Quota
+}
+
+// NewOpenWeather returns an open weather provider calling the API at cfg.BaseURL.
+// Calls rotate across the keys in the key ring and are accounted against the quota.
+func NewOpenWeather(cfg *Config, client Client, keys *KeyRing, quota Quota) *OpenWeather {
+	return &OpenWeather{
+		cfg:    cfg,
+		client: client,
+		keys:   keys,
+		quota:  quota,
+	}
+}
+
+// Name returns "openweather".
+func (o *OpenWeather) Name() string {
+	return OpenWeatherProvider
+}
+
+// Current calls the /weather endpoint, locations are looked up by coordinates, zip code or city.
+func (o *OpenWeather) Current(ctx context.Context, loc Location, opts Options) (*Observation, error) {
+	query := url.Values{}
+	switch {
+	case loc.Coord != nil:
+		query.Set("lat", fmt.Sprint(loc.Coord.Lat))
+		query.Set("lon", fmt.Sprint(loc.Coord.Lon))
+	case loc.Zip != "":
+		query.Set("zip", loc.String())
+	default:
+		query.Set("q", loc.String())
+	}
+	query.Set("units", string(opts.Units))
+	query.Set("lang", opts.Lang)
+
+	var owr OpenWeatherResponse
+	if err := o.get(ctx, "/weather", query, &owr); err != nil {
+		return nil, err
+	}
+
+	if owr.Cod != 0 && owr.Cod != http.StatusOK {
+		return nil, &UpstreamError{Provider: OpenWeatherProvider, Code: int(owr.Cod), Message: owr.Message}
+	}
+
+	return owr.observation(loc, opts.Units), nil
+}
+
+// Forecast calls the /onecall endpoint.
+func (o *OpenWeather) Forecast(ctx context.Context, coord Coord, opts Options) (*Forecast, error) {
+	query := url.Values{}
+	query.Set("lat", fmt.Sprint(coord.Lat))
+	query.Set("lon", fmt.Sprint(coord.Lon))
+	query.Set("units", string(opts.Units))
+	query.Set("lang", opts.Lang)
+
+	var ocr OneCallResponse
+	if err := o.get(ctx, "/onecall", query, &ocr); err != nil {
+		return nil, err
+	}
+
+	return ocr.forecast(coord, opts.Units), nil
+}
+
+// Geocode calls the /weather endpoint, which reports the coordinates of the location along with its current conditions.
+func (o *OpenWeather) Geocode(ctx context.Context, loc Location) (Coord, error) {
+	obs, err := o.Current(ctx, loc, Options{Units: Metric, Lang: "en"})
+	if err != nil {
+		return Coord{}, err
+	}
+
+	return obs.Coord, nil
+}
+
+// get calls an open weather endpoint and decodes the response into v.
+// Every call is accounted against the quota with the priority carried by the context.
+// A key rejected by the upstream with 401 or 429 is suspended and the call is retried with the next key.
+func (o *OpenWeather) get(ctx context.Context, endpoint string, query url.Values, v interface{}) error {
+	for attempt := 0; attempt < o.keys.Len(); attempt++ {
+		key, err := o.keys.Next()
+		if err != nil {
+			return err
+		}
+
+		if err := o.quota.Reserve(PriorityFrom(ctx)); err != nil {
+			return err
+		}
+
+		query.Set("appid", key)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s%s?%s", o.cfg.BaseURL, endpoint, query.Encode()), nil)
+		if err != nil {
+			return RedactError(err)
+		}
+
+		response, err := o.client.Do(req)
+		if err != nil {
+			return RedactError(err)
+		}
+
+		if response.StatusCode == http.StatusUnauthorized || response.StatusCode == http.StatusTooManyRequests {
+			response.Body.Close()
+			o.keys.Suspend(key, o.cfg.APIKeyCooldown)
+			continue
+		}
+
+		// Error payloads are reported rather than decoded as data, so that the request can fail over to another provider
+		if response.StatusCode >= http.StatusBadRequest {
+			var payload openWeatherError
+			json.NewDecoder(response.Body).Decode(&payload)
+			response.Body.Close()
+			return &UpstreamError{Provider: OpenWeatherProvider, Code: response.StatusCode, Message: payload.Message}
+		}
+
+		err = json.NewDecoder(response.Body).Decode(v)
+		response.Body.Close()
+		return err
+	}
+
+	return ErrNoAPIKey
+}
+
+// observation maps a /weather response into the canonical model.
+func (o *OpenWeatherResponse) observation(loc Location, units Unit) *Observation {
+	obs := Observation{
+		Location:    Location{City: loc.City, Country: o.Sys.Country, Zip: loc.Zip, Coord: loc.Coord},
+		Name:        o.Name,
+		Coord:       o.Coord,
+		Units:       units,
+		Time:        unixTime(int64(o.Dt)),
+		UTCOffset:   o.Timezone,
+		Temperature: o.Main.Temp,
+		FeelsLike:   o.Main.FeelsLike,
+		Pressure:    float64(o.Main.Pressure),
+		Humidity:    float64(o.Main.Humidity),
+		CloudCover:  float64(o.Clouds.All),
+		WindSpeed:   o.Wind.Speed,
+		WindDeg:     float64(o.Wind.Deg),
+		Sunrise:     unixTime(o.Sys.Sunrise),
+		Sunset:      unixTime(o.Sys.Sunset),
+	}
+
+	if len(o.Weather) > 0 {
+		obs.Condition = o.Weather[0].Main
+		obs.Description = o.Weather[0].Description
+	}
+
+	return &obs
+}
+
+// forecast maps a /onecall response into the canonical model.
+func (o *OneCallResponse) forecast(coord Coord, units Unit) *Forecast {
+	f := Forecast{
+		Coord:  coord,
+		Units:  units,
+		Hourly: make([]HourlyPoint, len(o.Hourly)),
+		Daily:  make([]DailyForecast, len(o.Daily)),
+		Alerts: make([]Alert, len(o.Alerts)),
+	}
+
+	for i, h := range o.Hourly {
+		f.Hourly[i] = HourlyPoint{
+			Time:                     unixTime(int64(h.Dt)),
+			Temperature:              h.Temp,
+			FeelsLike:                h.FeelsLike,
+			Pressure:                 float64(h.Pressure),
+			Humidity:                 float64(h.Humidity),
+			CloudCover:               float64(h.Clouds),
+			WindSpeed:                h.WindSpeed,
+			WindDeg:                  float64(h.WindDeg),
+			PrecipitationProbability: h.Pop,
+			Precipitation:            h.Rain.OneH,
+		}
+		if len(h.Weather) > 0 {
+			f.Hourly[i].Condition = h.Weather[0].Main
+			f.Hourly[i].Description = h.Weather[0].Description
+		}
+	}
+
+	for i, d := range o.Daily {
+		f.Daily[i] = DailyForecast{
+			Time:                     unixTime(int64(d.Dt)),
+			TempMin:                  d.Temp.Min,
+			TempMax:                  d.Temp.Max,
+			Pressure:                 float64(d.Pressure),
+			Humidity:                 float64(d.Humidity),
+			CloudCover:               float64(d.Clouds),
+			WindSpeed:                d.WindSpeed,
+			WindDeg:                  float64(d.WindDeg),
+			PrecipitationProbability: d.Pop,
+			Precipitation:            d.Rain,
+			UVIndex:                  d.Uvi,
+			Sunrise:                  unixTime(int64(d.Sunrise)),
+			Sunset:                   unixTime(int64(d.Sunset)),
+		}
+		if len(d.Weather) > 0 {
+			f.Daily[i].Condition = d.Weather[0].Main
+			f.Daily[i].Description = d.Weather[0].Description
+		}
+	}
+
+	for i, a := range o.Alerts {
+		f.Alerts[i] = Alert{
+			Sender:      a.SenderName,
+			Event:       a.Event,
+			Start:       unixTime(int64(a.Start)),
+			End:         unixTime(int64(a.End)),
+			Description: a.Description,
+		}
+	}
+
+	return &f
+}
@@ -0,0 +1,146 @@
+package weather
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type unlimitedQuota struct {
+	mu    sync.Mutex
+	calls int
+}
+
+func (q *unlimitedQuota) Reserve(p Priority) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.calls++
+	return nil
+}
+
+func TestOpenWeather(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "key", r.FormValue("appid"))
+		assert.Equal(t, "imperial", r.FormValue("units"))
+		assert.Equal(t, "es", r.FormValue("lang"))
+
+		switch r.URL.Path {
+		case "/data/2.5/weather":
+			assert.Equal(t, "bogota,CO", r.FormValue("q"))
+			fmt.Fprint(w, `{"coord": {"lon": -74.08, "lat": 4.61}, "name": "Bogotá", "main": {"temp": 68}}`)
+		case "/data/2.5/onecall":
+			assert.Equal(t, "4.61", r.FormValue("lat"))
+			assert.Equal(t, "-74.08", r.FormValue("lon"))
+			fmt.Fprint(w, `{
+				"hourly": [{"dt": 1608843600, "temp": 66, "pop": 0.5, "rain": {"1h": 0.3}, "weather": [{"main": "Rain", "description": "lluvia ligera"}]}],
+				"daily": [{"dt": 1608825600, "temp": {"min": 48, "max": 67}, "pop": 0.97, "rain": 6.4}],
+				"alerts": [{"sender_name": "IDEAM", "event": "Lluvias", "start": 1608825600, "end": 1608912000}]
+			}`)
+		default:
+			t.Errorf("Unexpected request %s", r.URL)
+		}
+	}))
+	defer server.Close()
+
+	var quota unlimitedQuota
+	cfg := Config{BaseURL: server.URL + "/data/2.5"}
+	provider := NewOpenWeather(&cfg, http.DefaultClient, NewKeyRing([]APIKey{{Key: "key"}}), &quota)
+	ctx := context.Background()
+	opts := Options{Units: Imperial, Lang: "es"}
+
+	obs, err := provider.Current(ctx, Location{City: "bogota", Country: "CO"}, opts)
+	assert.Nil(t, err)
+	assert.Equal(t, "Bogotá", obs.Name)
+	assert.Equal(t, 68.0, obs.Temperature)
+	assert.Equal(t, Imperial, obs.Units)
+	assert.True(t, obs.Time.IsZero())
+
+	f, err := provider.Forecast(ctx, obs.Coord, opts)
+	assert.Nil(t, err)
+	assert.Equal(t, &Forecast{
+		Coord: Coord{Lat: 4.61, Lon: -74.08},
+		Units: Imperial,
+		Hourly: []HourlyPoint{{
+			Time:                     time.Unix(1608843600, 0).UTC(),
+			Temperature:              66,
+			PrecipitationProbability: 0.5,
+			Precipitation:            0.3,
+			Condition:                "Rain",
+			Description:              "lluvia ligera",
+		}},
+		Daily:  []DailyForecast{{Time: time.Unix(1608825600, 0).UTC(), TempMin: 48, TempMax: 67, PrecipitationProbability: 0.97, Precipitation: 6.4}},
+		Alerts: []Alert{{Sender: "IDEAM", Event: "Lluvias", Start: time.Unix(1608825600, 0).UTC(), End: time.Unix(1608912000, 0).UTC()}},
+	}, f)
+
+	assert.Equal(t, 2, quota.calls)
+}
+
+func TestOpenWeatherErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.FormValue("q") {
+		case "atlantis,GR":
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"cod": "404", "message": "city not found"}`)
+		case "bogota,CO":
+			fmt.Fprint(w, `{"cod": "500", "message": "internal error"}`)
+		default:
+			fmt.Fprint(w, `{"cod": 200, "name": "Medellín"}`)
+		}
+	}))
+	defer server.Close()
+
+	cfg := Config{BaseURL: server.URL}
+	provider := NewOpenWeather(&cfg, http.DefaultClient, NewKeyRing([]APIKey{{Key: "key"}}), &unlimitedQuota{})
+	ctx := context.Background()
+	opts := Options{Units: Metric, Lang: "en"}
+
+	_, err := provider.Current(ctx, Location{City: "atlantis", Country: "GR"}, opts)
+	assert.Equal(t, &UpstreamError{Provider: OpenWeatherProvider, Code: 404, Message: "city not found"}, err)
+	assert.True(t, errors.Is(err, ErrLocationNotFound))
+
+	// Error codes are also reported when the payload is sent with a successful status
+	_, err = provider.Current(ctx, Location{City: "bogota", Country: "CO"}, opts)
+	assert.Equal(t, &UpstreamError{Provider: OpenWeatherProvider, Code: 500, Message: "internal error"}, err)
+
+	obs, err := provider.Current(ctx, Location{City: "medellin", Country: "CO"}, opts)
+	assert.Nil(t, err)
+	assert.Equal(t, "Medellín", obs.Name)
+}
+
+func TestOpenWeatherLocations(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.FormValue("zip") != "":
+			assert.Equal(t, "10001,US", r.FormValue("zip"))
+			assert.Empty(t, r.FormValue("q"))
+		case r.FormValue("lat") != "":
+			assert.Equal(t, "4.61", r.FormValue("lat"))
+			assert.Equal(t, "-74.08", r.FormValue("lon"))
+			assert.Empty(t, r.FormValue("q"))
+		default:
+			t.Errorf("Unexpected request %s", r.URL)
+		}
+		fmt.Fprint(w, `{"coord": {"lon": -74.08, "lat": 4.61}, "name": "Somewhere"}`)
+	}))
+	defer server.Close()
+
+	cfg := Config{BaseURL: server.URL}
+	provider := NewOpenWeather(&cfg, http.DefaultClient, NewKeyRing([]APIKey{{Key: "key"}}), &unlimitedQuota{})
+	ctx := context.Background()
+	opts := Options{Units: Metric, Lang: "en"}
+
+	for _, loc := range []Location{{Zip: "10001", Country: "US"}, {Coord: &Coord{Lat: 4.61, Lon: -74.08}}} {
+		obs, err := provider.Current(ctx, loc, opts)
+		assert.Nil(t, err)
+		assert.Equal(t, loc.Zip, obs.Location.Zip)
+		assert.Equal(t, loc.Coord, obs.Location.Coord)
+	}
+}
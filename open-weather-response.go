@@ -2,31 +2,10 @@ package weather
 
 import (
 	"fmt"
-	"math"
+	"strconv"
 	"strings"
-	"time"
 )
 
-var directions = []string{
-	"north",
-	"north-northeast",
-	"northeast",
-	"east-northeast",
-	"east",
-	"east-southeast",
-	"southeast",
-	"south-southeast",
-	"south",
-	"south-southwest",
-	"southwest",
-	"west-southwest",
-	"west",
-	"west-northwest",
-	"northwest",
-	"north-northwest",
-	"north",
-}
-
 // OpenWeatherResponse is the object for the response from open weather's /weather endpoint.
 type OpenWeatherResponse struct {
 	Coord      Coord     `json:"coord"`
@@ -41,13 +20,34 @@ type OpenWeatherResponse struct {
 	Timezone   int       `json:"timezone"`
 	ID         int       `json:"id"`
 	Name       string    `json:"name"`
-	Cod        int       `json:"cod"`
+	Cod        Code      `json:"cod"`
+	Message    string    `json:"message"`
 }
 
-// Coord holds the location coordinate data.
-type Coord struct {
-	Lon float64 `json:"lon"`
-	Lat float64 `json:"lat"`
+// Code is the status code in an open weather payload, which is sent as a number by some endpoints and as a string by others.
+type Code int
+
+// UnmarshalJSON decodes a code sent as a number or as a string.
+func (c *Code) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	if s == "" || s == "null" {
+		*c = 0
+		return nil
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("Invalid open weather code %s", b)
+	}
+	*c = Code(n)
+
+	return nil
+}
+
+// openWeatherError is the payload of an open weather error response.
+type openWeatherError struct {
+	Cod     Code   `json:"cod"`
+	Message string `json:"message"`
 }
 
 // Weather provides a description of the current weather.
@@ -87,62 +87,3 @@ type Sys struct {
 	Sunrise int64  `json:"sunrise"`
 	Sunset  int64  `json:"sunset"`
 }
-
-// ToHumanReadable converts an open weather model to a more human readable model.
-func (o *OpenWeatherResponse) ToHumanReadable(unitSymbol string) *HumanReadableResponse {
-	resp := HumanReadableResponse{
-		LocationName:   fmt.Sprintf("%s, %s", strings.Title(o.Name), strings.ToUpper(o.Sys.Country)),
-		Temperature:    fmt.Sprintf("%g %s", o.Main.Temp, unitSymbol),
-		Wind:           fmt.Sprintf("%s, %g m/s, %s", windDescription(o.Wind.Speed), o.Wind.Speed, windDirection(o.Wind.Deg)),
-		Pressure:       fmt.Sprintf("%d hpa", o.Main.Pressure),
-		Humidity:       fmt.Sprintf("%d%%", o.Main.Humidity),
-		Sunrise:        time.Unix(o.Sys.Sunrise, 0).Format("15:04"),
-		Sunset:         time.Unix(o.Sys.Sunset, 0).Format("15:04"),
-		GeoCoordinates: fmt.Sprintf("[%g, %g]", o.Coord.Lat, o.Coord.Lon),
-		RequestedTime:  time.Now().Format("2006-01-02 15:04:05"),
-	}
-
-	if len(o.Weather) > 0 {
-		resp.Cloudiness = o.Weather[0].Description
-	}
-
-	return &resp
-}
-
-// windDescription uses the following scale to determine wind speed: https://en.wikipedia.org/wiki/Beaufort_scale
-func windDescription(speed float64) string {
-	switch {
-	case speed <= .5:
-		return "Calm"
-	case speed <= 1.5:
-		return "Light air"
-	case speed <= 3.3:
-		return "Light breeze"
-	case speed <= 5.5:
-		return "Gentle breeze"
-	case speed <= 7.9:
-		return "Moderate breeze"
-	case speed <= 10.7:
-		return "Fresh breeze"
-	case speed <= 13.8:
-		return "Strong breeze"
-	case speed <= 17.1:
-		return "High wind"
-	case speed <= 20.7:
-		return "Gale"
-	case speed <= 24.4:
-		return "Strong/severe gale"
-	case speed <= 28.4:
-		return "Storm"
-	case speed <= 32.6:
-		return "Violent storm"
-	}
-
-	return "Hurricane force"
-}
-
-// windDirection converts degrees to wind direction
-func windDirection(direction int) string {
-	direction = direction % 360
-	return directions[int(math.Round(float64(direction)/22.5))]
-}
@@ -0,0 +1,59 @@
+package weather
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// List of provider names.
+const (
+	OpenWeatherProvider = "openweather"
+	OpenMeteoProvider   = "openmeteo"
+)
+
+// Client is the subset of *http.Client used by providers to reach their upstream APIs.
+type Client interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Quota accounts upstream calls against the limits of an upstream plan.
+type Quota interface {
+	// Reserve records a call of the given priority, or returns ErrQuotaExceeded when the call is not allowed.
+	Reserve(p Priority) error
+}
+
+// Options holds the request options understood by every provider.
+type Options struct {
+	Units Unit
+	Lang  string
+}
+
+// Provider is a source of weather data.
+// Every provider maps its upstream responses into the canonical model.
+type Provider interface {
+	// Name identifies the provider, e.g. "openweather".
+	Name() string
+
+	// Current returns the current conditions at the location.
+	Current(ctx context.Context, loc Location, opts Options) (*Observation, error)
+
+	// Forecast returns the hourly and daily forecast at the coordinates, the daily forecast starts with today.
+	Forecast(ctx context.Context, coord Coord, opts Options) (*Forecast, error)
+
+	// Geocode returns the coordinates of the location.
+	Geocode(ctx context.Context, loc Location) (Coord, error)
+}
+
+// NewProvider returns the provider with the given name.
+// The key ring and quota only apply to providers that need an API key.
+func NewProvider(name string, cfg *Config, client Client, keys *KeyRing, quota Quota) (Provider, error) {
+	switch name {
+	case OpenWeatherProvider:
+		return NewOpenWeather(cfg, client, keys, quota), nil
+	case OpenMeteoProvider:
+		return NewOpenMeteo(cfg, client), nil
+	}
+
+	return nil, fmt.Errorf("Unknown provider %q, use: %s, %s", name, OpenWeatherProvider, OpenMeteoProvider)
+}
@@ -0,0 +1,53 @@
+package weather
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrQuotaExceeded is returned when an upstream call would exceed the upstream plan's limits.
+var ErrQuotaExceeded = errors.New("Upstream quota exceeded, please try again later")
+
+// Priority distinguishes upstream calls made to answer a client from optional background work.
+type Priority int
+
+// List of priorities.
+const (
+	// Essential calls are needed to answer a client request and are only refused once a limit is reached.
+	Essential Priority = iota
+
+	// NonEssential calls, such as prefetching, are refused once usage gets near a limit.
+	NonEssential
+)
+
+type priorityKey struct{}
+
+// WithPriority returns a context carrying the priority of the upstream calls made with it.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// PriorityFrom returns the priority carried by the context, calls are essential by default.
+func PriorityFrom(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return p
+	}
+
+	return Essential
+}
+
+// Usage reports the upstream calls made within the rolling minute and the current calendar month.
+// A limit of zero means there is no limit.
+type Usage struct {
+	Minute      int       `json:"minute"`
+	MinuteLimit int       `json:"minute_limit"`
+	Month       int       `json:"month"`
+	MonthLimit  int       `json:"month_limit"`
+	MonthStart  time.Time `json:"month_start"`
+}
+
+// Exhausted reports whether either limit is reached, further calls are refused whatever their priority.
+func (u Usage) Exhausted() bool {
+	return (u.MinuteLimit > 0 && u.Minute >= u.MinuteLimit) || (u.MonthLimit > 0 && u.Month >= u.MonthLimit)
+}
@@ -0,0 +1,107 @@
+package weather
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Redacted is the text secrets are replaced with.
+const Redacted = "REDACTED"
+
+// minSecretLength is the length below which secrets are not registered, as replacing them would mangle unrelated text.
+// Such secrets are still redacted from API key query parameters.
+const minSecretLength = 8
+
+// appIDParam matches the API key query parameter of upstream URLs, including its URL encoded form in error messages.
+var appIDParam = regexp.MustCompile(`(?i)(appid(=|%3D))[^&\s"'%]*`)
+
+var secrets = struct {
+	sync.RWMutex
+	values map[string]struct{}
+}{values: make(map[string]struct{})}
+
+// RegisterSecret makes Redact remove every occurrence of the secret.
+func RegisterSecret(secret string) {
+	if len(secret) < minSecretLength {
+		return
+	}
+
+	secrets.Lock()
+	defer secrets.Unlock()
+
+	secrets.values[secret] = struct{}{}
+}
+
+// Redact removes registered secrets and the values of API key query parameters from the text.
+func Redact(s string) string {
+	s = appIDParam.ReplaceAllString(s, "${1}"+Redacted)
+
+	secrets.RLock()
+	defer secrets.RUnlock()
+
+	for secret := range secrets.values {
+		s = strings.Replace(s, secret, Redacted, -1)
+	}
+
+	return s
+}
+
+// RedactError wraps the error so that its message is redacted.
+// The original error can still be inspected with errors.Is and errors.As.
+func RedactError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var r *redactedError
+	if errors.As(err, &r) {
+		return err
+	}
+
+	return &redactedError{err}
+}
+
+type redactedError struct {
+	err error
+}
+
+func (e *redactedError) Error() string {
+	return Redact(e.err.Error())
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// RedactHook is a logrus hook that redacts secrets from log messages and fields.
+type RedactHook struct{}
+
+// Levels returns all log levels, as secrets must be redacted at every level.
+func (RedactHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire redacts the entry's message and its string and error fields.
+// The fields are copied rather than modified, as they may be shared with other entries.
+func (RedactHook) Fire(e *logrus.Entry) error {
+	e.Message = Redact(e.Message)
+
+	data := make(logrus.Fields, len(e.Data))
+	for k, v := range e.Data {
+		switch v := v.(type) {
+		case string:
+			data[k] = Redact(v)
+		case error:
+			data[k] = Redact(v.Error())
+		default:
+			data[k] = v
+		}
+	}
+	e.Data = data
+
+	return nil
+}
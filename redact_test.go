@@ -0,0 +1,57 @@
+package weather
+
+import (
+	"bytes"
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type RedactCase struct {
+	input    string
+	expected string
+}
+
+func TestRedact(t *testing.T) {
+	RegisterSecret("s3cr3t-registered")
+
+	cases := []RedactCase{
+		{"http://api/weather?q=bogota&appid=abc123&units=metric", "http://api/weather?q=bogota&appid=REDACTED&units=metric"},
+		{"http://api/weather?APPID=abc123", "http://api/weather?APPID=REDACTED"},
+		{`Get "http://api/onecall?appid=abc123": dial tcp: connection refused`, `Get "http://api/onecall?appid=REDACTED": dial tcp: connection refused`},
+		{"q%3Dbogota%26appid%3Dabc123%26units", "q%3Dbogota%26appid%3DREDACTED%26units"},
+		{"key is s3cr3t-registered.", "key is REDACTED."},
+		{"nothing to hide", "nothing to hide"},
+	}
+
+	for i := range cases {
+		assert.Equal(t, cases[i].expected, Redact(cases[i].input))
+	}
+}
+
+func TestRedactError(t *testing.T) {
+	cause := errors.New("connection refused")
+	err := RedactError(&url.Error{Op: "Get", URL: "http://api/weather?appid=abc123", Err: cause})
+
+	assert.Equal(t, `Get "http://api/weather?appid=REDACTED": connection refused`, err.Error())
+	assert.True(t, errors.Is(err, cause))
+	assert.Equal(t, err, RedactError(err))
+	assert.Nil(t, RedactError(nil))
+}
+
+func TestRedactHook(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&buf)
+	logger.AddHook(RedactHook{})
+
+	shared := logger.WithField("url", "http://api/weather?appid=abc123")
+	shared.WithError(errors.New("bad appid=abc123")).Error("Request to http://api/weather?appid=abc123 failed")
+
+	assert.NotContains(t, buf.String(), "abc123")
+	assert.Contains(t, buf.String(), "appid=REDACTED")
+	assert.Equal(t, "http://api/weather?appid=abc123", shared.Data["url"])
+}
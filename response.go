@@ -1,6 +1,33 @@
 package weather
 
-// HumanReadableResponse is the more human readable response translated from the open weather response.
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+var directions = []string{
+	"north",
+	"north-northeast",
+	"northeast",
+	"east-northeast",
+	"east",
+	"east-southeast",
+	"southeast",
+	"south-southeast",
+	"south",
+	"south-southwest",
+	"southwest",
+	"west-southwest",
+	"west",
+	"west-northwest",
+	"northwest",
+	"north-northwest",
+	"north",
+}
+
+// HumanReadableResponse is the more human readable response rendered from the canonical model.
 type HumanReadableResponse struct {
 	LocationName   string `json:"location_name"`
 	Temperature    string `json:"temperature"`
@@ -12,5 +39,88 @@ type HumanReadableResponse struct {
 	Sunset         string `json:"sunset"`
 	GeoCoordinates string `json:"geo_coordinates"`
 	RequestedTime  string `json:"requested_time"`
-	Forecast       *Daily `json:"forecast,omitempty"`
+
+	// UTCOffset is the offset of the local time of the location from UTC in seconds.
+	UTCOffset int `json:"utc_offset,omitempty"`
+
+	// Provider and ForecastProvider name the providers that served the current conditions and the forecast.
+	Provider         string         `json:"provider,omitempty"`
+	ForecastProvider string         `json:"forecast_provider,omitempty"`
+	Forecast         *DailyForecast `json:"forecast,omitempty"`
+
+	// Hourly is the hourly forecast of the local day of Forecast, as far as the provider forecasts hours.
+	Hourly []HourlyPoint `json:"hourly,omitempty"`
+
+	// Ensemble combines the forecasts of every provider, it is only set when requested.
+	Ensemble *EnsembleDaily `json:"ensemble,omitempty"`
+}
+
+// ToHumanReadable converts an observation to a more human readable model.
+// Sunrise and sunset are shown in local time, unknown times are left empty.
+func (o *Observation) ToHumanReadable(unitSymbol string) *HumanReadableResponse {
+	name := strings.Title(o.Name)
+	if o.Location.Country != "" {
+		name += ", " + strings.ToUpper(o.Location.Country)
+	}
+
+	return &HumanReadableResponse{
+		LocationName:   name,
+		Temperature:    fmt.Sprintf("%g %s", o.Temperature, unitSymbol),
+		Wind:           fmt.Sprintf("%s, %g m/s, %s", windDescription(o.WindSpeed), o.WindSpeed, windDirection(int(math.Round(o.WindDeg)))),
+		Cloudiness:     o.Description,
+		Pressure:       fmt.Sprintf("%g hpa", o.Pressure),
+		Humidity:       fmt.Sprintf("%g%%", o.Humidity),
+		Sunrise:        clock(o.Sunrise),
+		Sunset:         clock(o.Sunset),
+		GeoCoordinates: fmt.Sprintf("[%g, %g]", o.Coord.Lat, o.Coord.Lon),
+		RequestedTime:  time.Now().Format("2006-01-02 15:04:05"),
+		UTCOffset:      o.UTCOffset,
+	}
+}
+
+// clock formats the time of day in local time, the zero time is formatted as an empty string.
+func clock(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.Local().Format("15:04")
+}
+
+// windDescription uses the following scale to determine wind speed: https://en.wikipedia.org/wiki/Beaufort_scale
+func windDescription(speed float64) string {
+	switch {
+	case speed <= .5:
+		return "Calm"
+	case speed <= 1.5:
+		return "Light air"
+	case speed <= 3.3:
+		return "Light breeze"
+	case speed <= 5.5:
+		return "Gentle breeze"
+	case speed <= 7.9:
+		return "Moderate breeze"
+	case speed <= 10.7:
+		return "Fresh breeze"
+	case speed <= 13.8:
+		return "Strong breeze"
+	case speed <= 17.1:
+		return "High wind"
+	case speed <= 20.7:
+		return "Gale"
+	case speed <= 24.4:
+		return "Strong/severe gale"
+	case speed <= 28.4:
+		return "Storm"
+	case speed <= 32.6:
+		return "Violent storm"
+	}
+
+	return "Hurricane force"
+}
+
+// windDirection converts degrees to wind direction, degrees outside 0 to 360 are wrapped around.
+func windDirection(direction int) string {
+	direction = (direction%360 + 360) % 360
+	return directions[int(math.Round(float64(direction)/22.5))]
 }
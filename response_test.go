@@ -51,6 +51,10 @@ func TestWindDirection(t *testing.T) {
 		{340, "north-northwest"},
 		{350, "north"},
 		{360, "north"},
+		{370, "north"},
+		{-10, "north"},
+		{-90, "west"},
+		{-730, "north"},
 	}
 
 	for i := range cases {
@@ -99,34 +103,25 @@ func TestWindDescription(t *testing.T) {
 }
 
 type HumanReadableCase struct {
-	input  OpenWeatherResponse
+	input  Observation
 	output *HumanReadableResponse
 }
 
 func TestToHumanReadable(t *testing.T) {
 	cases := []HumanReadableCase{
 		{
-			OpenWeatherResponse{
-				Name: "Bogota",
-				Sys: Sys{
-					Country: "CO",
-					Sunrise: 1608202626,
-					Sunset:  1608245303,
-				},
-				Main: Main{
-					Temp:     20,
-					Pressure: 1000,
-					Humidity: 50,
-				},
-				Wind: Wind{
-					Speed: 3,
-					Deg:   10,
-				},
-				Coord: Coord{
-					Lat: 4.61,
-					Lon: -74.08,
-				},
-				Weather: []Weather{{Description: "Scattered clouds"}},
+			Observation{
+				Location:    Location{City: "bogota", Country: "CO"},
+				Name:        "Bogota",
+				Coord:       Coord{Lat: 4.61, Lon: -74.08},
+				Temperature: 20,
+				Pressure:    1000,
+				Humidity:    50,
+				WindSpeed:   3,
+				WindDeg:     10,
+				Description: "Scattered clouds",
+				Sunrise:     time.Unix(1608202626, 0).UTC(),
+				Sunset:      time.Unix(1608245303, 0).UTC(),
 			},
 			&HumanReadableResponse{
 				LocationName:   "Bogota, CO",
@@ -141,6 +136,18 @@ func TestToHumanReadable(t *testing.T) {
 				Cloudiness:     "Scattered clouds",
 			},
 		},
+		{
+			Observation{Location: Location{City: "bogota", Country: "CO"}, Name: "Bogota"},
+			&HumanReadableResponse{
+				LocationName:   "Bogota, CO",
+				Temperature:    fmt.Sprintf("%g %s", 0.0, Metric.Symbol()),
+				Wind:           "Calm, 0 m/s, north",
+				Pressure:       "0 hpa",
+				Humidity:       "0%",
+				GeoCoordinates: "[0, 0]",
+				RequestedTime:  time.Now().Format("2006-01-02 15:04:05"),
+			},
+		},
 	}
 
 	for i := range cases {
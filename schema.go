@@ -0,0 +1,167 @@
+package weather
+
+// SchemaV1 is the JSON schema of version v1 of the canonical model and of the /weather response.
+// The schema is published by the server at /schema/v1, every type of the model is listed under $defs.
+const SchemaV1 = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "$id": "/schema/v1",
+  "title": "Weather response",
+  "description": "The response of the /weather endpoint, version v1.",
+  "$ref": "#/$defs/weather_response",
+  "$defs": {
+    "weather_response": {
+      "type": "object",
+      "required": ["location_name", "temperature", "wind", "cloudiness", "pressure", "humidity", "sunrise", "sunset", "geo_coordinates", "requested_time"],
+      "properties": {
+        "location_name": {"type": "string", "description": "City and country code, e.g. \"Bogotá, CO\". The country is omitted when unknown."},
+        "temperature": {"type": "string", "description": "Temperature with its unit, e.g. \"18 °C\"."},
+        "wind": {"type": "string", "description": "Wind description, speed and direction."},
+        "cloudiness": {"type": "string"},
+        "pressure": {"type": "string", "description": "Pressure in hpa, e.g. \"1024 hpa\"."},
+        "humidity": {"type": "string", "description": "Relative humidity, e.g. \"48%\"."},
+        "sunrise": {"type": "string", "description": "Local time of day, empty when unknown."},
+        "sunset": {"type": "string", "description": "Local time of day, empty when unknown."},
+        "geo_coordinates": {"type": "string", "description": "Latitude and longitude, e.g. \"[4.61, -74.08]\"."},
+        "requested_time": {"type": "string"},
+        "utc_offset": {"type": "integer", "description": "Offset of the local time of the location from UTC in seconds, omitted for UTC."},
+        "provider": {"type": "string", "description": "The provider that served the current conditions."},
+        "forecast_provider": {"type": "string", "description": "The provider that served the forecast."},
+        "forecast": {"$ref": "#/$defs/daily_forecast"},
+        "hourly": {"type": "array", "items": {"$ref": "#/$defs/hourly_point"}, "description": "Hourly forecast of the local day of the forecast, as far as the provider forecasts hours."},
+        "ensemble": {"$ref": "#/$defs/ensemble_daily"}
+      }
+    },
+    "location": {
+      "type": "object",
+      "required": ["city", "country"],
+      "properties": {
+        "city": {"type": "string"},
+        "country": {"type": "string", "description": "Two letter ISO 3166 country code."},
+        "zip": {"type": "string", "description": "Postal code, used instead of the city when present."},
+        "coord": {"$ref": "#/$defs/coord", "description": "Coordinates, used instead of the city and zip code when present."}
+      }
+    },
+    "coord": {
+      "type": "object",
+      "required": ["lon", "lat"],
+      "properties": {
+        "lon": {"type": "number", "minimum": -180, "maximum": 180},
+        "lat": {"type": "number", "minimum": -90, "maximum": 90}
+      }
+    },
+    "units": {
+      "enum": ["standard", "metric", "imperial"],
+      "description": "Temperatures are in kelvin, celsius or fahrenheit, wind speeds in meters per second or miles per hour."
+    },
+    "observation": {
+      "type": "object",
+      "description": "Current conditions at a location. Times are in UTC.",
+      "required": ["location", "name", "coord", "units", "time", "utc_offset", "temperature", "feels_like", "pressure", "humidity", "cloud_cover", "wind_speed", "wind_deg", "condition", "description", "sunrise", "sunset"],
+      "properties": {
+        "location": {"$ref": "#/$defs/location"},
+        "name": {"type": "string"},
+        "coord": {"$ref": "#/$defs/coord"},
+        "units": {"$ref": "#/$defs/units"},
+        "time": {"type": "string", "format": "date-time"},
+        "utc_offset": {"type": "integer", "description": "Offset of the local time from UTC in seconds."},
+        "temperature": {"type": "number"},
+        "feels_like": {"type": "number"},
+        "pressure": {"type": "number", "description": "Sea level pressure in hpa."},
+        "humidity": {"type": "number", "minimum": 0, "maximum": 100},
+        "cloud_cover": {"type": "number", "minimum": 0, "maximum": 100},
+        "wind_speed": {"type": "number", "minimum": 0},
+        "wind_deg": {"type": "number", "minimum": 0, "maximum": 360},
+        "condition": {"type": "string", "description": "Group of the conditions, e.g. \"Rain\"."},
+        "description": {"type": "string"},
+        "sunrise": {"type": "string", "format": "date-time"},
+        "sunset": {"type": "string", "format": "date-time"}
+      }
+    },
+    "forecast": {
+      "type": "object",
+      "required": ["coord", "units", "hourly", "daily", "alerts"],
+      "properties": {
+        "coord": {"$ref": "#/$defs/coord"},
+        "units": {"$ref": "#/$defs/units"},
+        "hourly": {"type": ["array", "null"], "items": {"$ref": "#/$defs/hourly_point"}},
+        "daily": {"type": ["array", "null"], "items": {"$ref": "#/$defs/daily_forecast"}},
+        "alerts": {"type": ["array", "null"], "items": {"$ref": "#/$defs/alert"}}
+      }
+    },
+    "hourly_point": {
+      "type": "object",
+      "required": ["time", "temperature", "feels_like", "pressure", "humidity", "cloud_cover", "wind_speed", "wind_deg", "precipitation_probability", "precipitation", "condition", "description"],
+      "properties": {
+        "time": {"type": "string", "format": "date-time"},
+        "temperature": {"type": "number"},
+        "feels_like": {"type": "number"},
+        "pressure": {"type": "number"},
+        "humidity": {"type": "number", "minimum": 0, "maximum": 100},
+        "cloud_cover": {"type": "number", "minimum": 0, "maximum": 100},
+        "wind_speed": {"type": "number", "minimum": 0},
+        "wind_deg": {"type": "number", "minimum": 0, "maximum": 360},
+        "precipitation_probability": {"type": "number", "minimum": 0, "maximum": 1},
+        "precipitation": {"type": "number", "minimum": 0, "description": "Precipitation in millimeters."},
+        "condition": {"type": "string"},
+        "description": {"type": "string"}
+      }
+    },
+    "daily_forecast": {
+      "type": "object",
+      "required": ["time", "temp_min", "temp_max", "pressure", "humidity", "cloud_cover", "wind_speed", "wind_deg", "precipitation_probability", "precipitation", "uv_index", "condition", "description", "sunrise", "sunset"],
+      "properties": {
+        "time": {"type": "string", "format": "date-time"},
+        "temp_min": {"type": "number"},
+        "temp_max": {"type": "number"},
+        "pressure": {"type": "number"},
+        "humidity": {"type": "number", "minimum": 0, "maximum": 100},
+        "cloud_cover": {"type": "number", "minimum": 0, "maximum": 100},
+        "wind_speed": {"type": "number", "minimum": 0},
+        "wind_deg": {"type": "number", "minimum": 0, "maximum": 360},
+        "precipitation_probability": {"type": "number", "minimum": 0, "maximum": 1},
+        "precipitation": {"type": "number", "minimum": 0, "description": "Precipitation in millimeters."},
+        "uv_index": {"type": "number", "minimum": 0},
+        "condition": {"type": "string"},
+        "description": {"type": "string"},
+        "sunrise": {"type": "string", "format": "date-time"},
+        "sunset": {"type": "string", "format": "date-time"}
+      }
+    },
+    "alert": {
+      "type": "object",
+      "required": ["sender", "event", "start", "end", "description"],
+      "properties": {
+        "sender": {"type": "string"},
+        "event": {"type": "string"},
+        "start": {"type": "string", "format": "date-time"},
+        "end": {"type": "string", "format": "date-time"},
+        "description": {"type": "string"}
+      }
+    },
+    "spread": {
+      "type": "object",
+      "required": ["value", "min", "max"],
+      "properties": {
+        "value": {"type": "number", "description": "The median of the values reported by the providers."},
+        "min": {"type": "number"},
+        "max": {"type": "number"}
+      }
+    },
+    "ensemble_daily": {
+      "type": "object",
+      "required": ["time", "providers", "temp_min", "temp_max", "precipitation_probability", "precipitation", "wind_speed", "confidence"],
+      "properties": {
+        "time": {"type": "string", "format": "date-time"},
+        "providers": {"type": "array", "items": {"type": "string"}},
+        "temp_min": {"$ref": "#/$defs/spread"},
+        "temp_max": {"$ref": "#/$defs/spread"},
+        "precipitation_probability": {"$ref": "#/$defs/spread"},
+        "precipitation": {"$ref": "#/$defs/spread"},
+        "wind_speed": {"$ref": "#/$defs/spread"},
+        "confidence": {"enum": ["high", "medium", "low"]},
+        "disagreements": {"type": "array", "items": {"type": "string"}}
+      }
+    }
+  }
+}
+`
@@ -0,0 +1,65 @@
+package weather
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type schemaDef struct {
+	Required   []string                   `json:"required"`
+	Properties map[string]json.RawMessage `json:"properties"`
+}
+
+// TestSchemaV1 checks that the schema lists every field of the model, so the two cannot drift apart.
+func TestSchemaV1(t *testing.T) {
+	var schema struct {
+		Defs map[string]schemaDef `json:"$defs"`
+	}
+	if err := json.Unmarshal([]byte(SchemaV1), &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	types := map[string]interface{}{
+		"weather_response": HumanReadableResponse{},
+		"location":         Location{},
+		"coord":            Coord{},
+		"observation":      Observation{},
+		"forecast":         Forecast{},
+		"hourly_point":     HourlyPoint{},
+		"daily_forecast":   DailyForecast{},
+		"alert":            Alert{},
+		"spread":           Spread{},
+		"ensemble_daily":   EnsembleDaily{},
+	}
+
+	for name, v := range types {
+		def, ok := schema.Defs[name]
+		if !assert.True(t, ok, name) {
+			continue
+		}
+
+		var fields, required, properties []string
+		typ := reflect.TypeOf(v)
+		for i := 0; i < typ.NumField(); i++ {
+			tag := strings.Split(typ.Field(i).Tag.Get("json"), ",")
+			fields = append(fields, tag[0])
+			if len(tag) == 1 {
+				required = append(required, tag[0])
+			}
+		}
+
+		for property := range def.Properties {
+			properties = append(properties, property)
+		}
+
+		sort.Strings(fields)
+		sort.Strings(properties)
+		assert.Equal(t, fields, properties, name)
+		assert.Equal(t, required, def.Required, name)
+	}
+}
@@ -0,0 +1,471 @@
+package weather
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultLang is the language used when a query does not specify one.
+const DefaultLang = "en"
+
+// Service is the weather API as a Go library, independent of HTTP.
+// It calls the configured providers in order, validates their responses and caches observations and forecasts
+// according to the cache TTL policies of the config. The coordinates of every location are remembered.
+type Service struct {
+	cfg         atomic.Value // *Config
+	keys        *KeyRing
+	quota       Quota
+	providers   *ProviderChain
+	lang        string
+	data        *cache.Cache
+	coordinates *cache.Cache
+}
+
+// ServiceOption configures a service.
+type ServiceOption func(*Service)
+
+// WithQuota accounts the upstream calls of the service against the quota, by default calls are not limited.
+func WithQuota(q Quota) ServiceOption {
+	return func(s *Service) {
+		s.quota = q
+	}
+}
+
+// WithLang sets the language of the descriptions returned by Current and Forecast, english by default.
+func WithLang(lang string) ServiceOption {
+	return func(s *Service) {
+		s.lang = lang
+	}
+}
+
+// Query describes a weather lookup.
+type Query struct {
+	Location Location
+	Units    Unit
+	Lang     string
+	Forecast int  // The requested forecast day, -1 when no forecast was requested.
+	Ensemble bool // Whether the forecast is combined from every provider.
+	Refresh  bool // Whether cached observations and forecasts are bypassed.
+}
+
+// Key returns the key under which the result of the query may be cached.
+func (q Query) Key() string {
+	key := fmt.Sprintf("%s|%s|%s|%d", q.Location.Key(), q.Units, q.Lang, q.Forecast)
+	if q.Ensemble {
+		key += "|ensemble"
+	}
+
+	return key
+}
+
+// Report is the human readable result of a query.
+type Report struct {
+	Response *HumanReadableResponse
+
+	// Observed is the upstream observation time, it is zero when unknown.
+	Observed time.Time
+
+	// Expires is when the data of the report expires from the cache, it is zero when the data never expires.
+	Expires time.Time
+}
+
+// cachedObservation and cachedForecast are cache entries along with the provider that served them.
+type cachedObservation struct {
+	obs      *Observation
+	provider string
+}
+
+type cachedForecast struct {
+	forecast *Forecast
+	provider string
+}
+
+// unlimited is the quota of a service without one, it allows every call.
+type unlimited struct{}
+
+func (unlimited) Reserve(p Priority) error {
+	return nil
+}
+
+// NewService returns a service calling the providers named in the config in order, unknown names are skipped.
+// Open weather is used when no known provider is configured.
+func NewService(cfg *Config, client Client, opts ...ServiceOption) *Service {
+	s := &Service{
+		keys:        NewKeyRing(cfg.Keys()),
+		quota:       unlimited{},
+		lang:        DefaultLang,
+		data:        cache.New(cfg.CacheExpirationDur, time.Minute),
+		coordinates: cache.New(cache.NoExpiration, 0),
+	}
+	s.cfg.Store(cfg)
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	var providers []Provider
+	for _, name := range cfg.Providers {
+		provider, err := NewProvider(name, cfg, client, s.keys, s.quota)
+		if err != nil {
+			logrus.Warn(err)
+			continue
+		}
+		providers = append(providers, provider)
+	}
+
+	if len(providers) == 0 {
+		providers = append(providers, NewOpenWeather(cfg, client, s.keys, s.quota))
+	}
+	s.providers = NewProviderChain(providers, cfg.ProviderTimeout)
+
+	return s
+}
+
+// SetAPIKeys replaces the upstream API keys used by the service.
+func (s *Service) SetAPIKeys(keys []APIKey) {
+	s.keys.Replace(keys)
+}
+
+// Reload swaps in a new config: its API keys, units and cache TTL policies apply to the next lookups,
+// data already cached keeps its expiry. The providers and their settings are those of the config the service was created with.
+func (s *Service) Reload(cfg *Config) {
+	s.cfg.Store(cfg)
+	s.keys.Replace(cfg.Keys())
+}
+
+// config returns the current config of the service.
+func (s *Service) config() *Config {
+	return s.cfg.Load().(*Config)
+}
+
+// CacheStats are the numbers of entries cached by a service, expired entries excluded.
+// Ensemble forecasts count as forecasts.
+type CacheStats struct {
+	Observations int `json:"observations"`
+	Forecasts    int `json:"forecasts"`
+	Coordinates  int `json:"coordinates"`
+}
+
+// CacheStats returns the numbers of observations, forecasts and coordinates cached by the service.
+func (s *Service) CacheStats() CacheStats {
+	var stats CacheStats
+	for _, item := range s.data.Items() {
+		if _, ok := item.Object.(*cachedObservation); ok {
+			stats.Observations++
+		} else {
+			stats.Forecasts++
+		}
+	}
+	stats.Coordinates = s.coordinates.ItemCount()
+
+	return stats
+}
+
+// FlushCache removes the cached observations and forecasts. Coordinates are kept, locations do not move.
+func (s *Service) FlushCache() {
+	s.data.Flush()
+}
+
+// Health is the health of the providers of a service.
+// APIKeys and APIKeysInService count the open weather API keys, they are zero when open weather is not a provider.
+type Health struct {
+	Providers        []ProviderHealth `json:"providers"`
+	APIKeys          int              `json:"api_keys"`
+	APIKeysInService int              `json:"api_keys_in_service"`
+}
+
+// Available reports whether any provider is available.
+func (h Health) Available() bool {
+	for _, p := range h.Providers {
+		if p.Available {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Health returns the health of the providers of the service, in order.
+// Open weather is unavailable while every API key is out of service, e.g. after being rejected by the upstream.
+func (s *Service) Health() Health {
+	h := Health{Providers: s.providers.Health()}
+	for i := range h.Providers {
+		if h.Providers[i].Provider != OpenWeatherProvider {
+			continue
+		}
+
+		h.APIKeys, h.APIKeysInService = s.keys.Len(), s.keys.Available()
+		if h.APIKeysInService == 0 {
+			h.Providers[i].Available = false
+		}
+	}
+
+	return h
+}
+
+// Current returns the current conditions at the location in the configured units and language.
+func (s *Service) Current(ctx context.Context, loc Location) (*Observation, error) {
+	obs, _, _, err := s.current(ctx, loc.Normalize(), s.options(), false)
+	return obs, err
+}
+
+// Forecast returns the forecast at the location in the configured units and language, limited to the given number of days.
+// A number of days of zero or less returns every day forecast by the provider.
+func (s *Service) Forecast(ctx context.Context, loc Location, days int) (*Forecast, error) {
+	loc = loc.Normalize()
+	coord, err := s.coordinatesOf(ctx, loc, s.options())
+	if err != nil {
+		return nil, err
+	}
+
+	f, _, _, err := s.forecast(ctx, coord, s.options(), false)
+	if err != nil {
+		return nil, err
+	}
+
+	return limitDays(f, days), nil
+}
+
+// CachedCurrent returns the cached current conditions at the location in the configured units and language, it never
+// calls the upstream. It reports whether the conditions are cached.
+func (s *Service) CachedCurrent(loc Location) (*Observation, bool) {
+	opts := s.options()
+	cached, ok := s.data.Get(fmt.Sprintf("%s|%s|%s", loc.Normalize().Key(), opts.Units, opts.Lang))
+	if !ok {
+		return nil, false
+	}
+
+	return cached.(*cachedObservation).obs, true
+}
+
+// CachedForecast returns the cached forecast at the location in the configured units and language, limited to the
+// given number of days like Forecast, it never calls the upstream. It reports whether the forecast is cached.
+func (s *Service) CachedForecast(loc Location, days int) (*Forecast, bool) {
+	coord, ok := s.coordinates.Get(loc.Normalize().Key())
+	if !ok {
+		return nil, false
+	}
+
+	cached, ok := s.data.Get(forecastKey(coord.(Coord), s.options()))
+	if !ok {
+		return nil, false
+	}
+
+	return limitDays(cached.(*cachedForecast).forecast, days), true
+}
+
+// limitDays returns the forecast limited to the given number of days, every day when it is zero or less.
+func limitDays(f *Forecast, days int) *Forecast {
+	if 0 < days && days < len(f.Daily) {
+		limited := *f
+		limited.Daily = f.Daily[:days]
+		return &limited
+	}
+
+	return f
+}
+
+// Lookup returns the human readable report answering the query.
+// Forecasts need the location's coordinates, when these are known both upstream calls are made in parallel.
+func (s *Service) Lookup(ctx context.Context, q Query) (*Report, error) {
+	opts := Options{Units: q.Units, Lang: q.Lang}
+	if q.Ensemble {
+		return s.lookupEnsemble(ctx, q, opts)
+	}
+
+	var (
+		obs              *Observation
+		forecast         *Forecast
+		provider         string
+		forecastProvider string
+		expires          time.Time
+		forecastExpires  time.Time
+		currentErr       error
+		forecastErr      error
+	)
+
+	if q.Forecast < 0 {
+		obs, provider, expires, currentErr = s.current(ctx, q.Location, opts, q.Refresh)
+	} else if coord, ok := s.coordinates.Get(q.Location.Key()); ok {
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			forecast, forecastProvider, forecastExpires, forecastErr = s.forecast(ctx, coord.(Coord), opts, q.Refresh)
+		}()
+
+		obs, provider, expires, currentErr = s.current(ctx, q.Location, opts, q.Refresh)
+		wg.Wait()
+	} else {
+		obs, provider, expires, currentErr = s.current(ctx, q.Location, opts, q.Refresh)
+		if currentErr == nil {
+			forecast, forecastProvider, forecastExpires, forecastErr = s.forecast(ctx, obs.Coord, opts, q.Refresh)
+		}
+	}
+
+	if currentErr != nil {
+		return nil, currentErr
+	}
+
+	if forecastErr != nil {
+		return nil, forecastErr
+	}
+
+	hr := obs.ToHumanReadable(q.Units.Symbol())
+	hr.Provider = provider
+
+	if q.Forecast >= 0 {
+		if q.Forecast >= len(forecast.Daily) {
+			return nil, &ValidationError{Field: "daily", Value: len(forecast.Daily), Reason: fmt.Sprintf("has no day %d", q.Forecast)}
+		}
+		hr.Forecast = &forecast.Daily[q.Forecast]
+		hr.Hourly = hoursOf(forecast.Hourly, hr.Forecast.Time, obs.UTCOffset)
+		hr.ForecastProvider = forecastProvider
+		expires = earliest(expires, forecastExpires)
+	}
+
+	return &Report{Response: hr, Observed: obs.Time, Expires: expires}, nil
+}
+
+// lookupEnsemble answers a query for an ensemble forecast.
+// The forecast of the first provider in the chain is reported along with the combined forecast of every provider.
+func (s *Service) lookupEnsemble(ctx context.Context, q Query, opts Options) (*Report, error) {
+	obs, provider, expires, err := s.current(ctx, q.Location, opts, q.Refresh)
+	if err != nil {
+		return nil, err
+	}
+
+	key := fmt.Sprintf("ensemble|%s", forecastKey(obs.Coord, opts))
+	cached, forecastExpires, ok := s.data.GetWithExpiration(key)
+	if !ok || q.Refresh {
+		forecasts, err := s.providers.Ensemble(ctx, obs.Coord, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		s.data.Set(key, forecasts, s.config().CachePolicy(ForecastData).Expiry(time.Time{}, time.Now()))
+		cached, forecastExpires, _ = s.data.GetWithExpiration(key)
+	}
+
+	forecasts := cached.([]ProviderForecast)
+	if q.Forecast >= len(forecasts[0].Daily) {
+		return nil, &ValidationError{Field: "daily", Value: len(forecasts[0].Daily), Reason: fmt.Sprintf("has no day %d", q.Forecast)}
+	}
+	ensemble := CombineForecasts(forecasts, q.Units)
+
+	hr := obs.ToHumanReadable(q.Units.Symbol())
+	hr.Provider = provider
+	hr.Forecast = &forecasts[0].Daily[q.Forecast]
+	hr.ForecastProvider = forecasts[0].Provider
+	hr.Ensemble = &ensemble[q.Forecast]
+
+	return &Report{Response: hr, Observed: obs.Time, Expires: earliest(expires, forecastExpires)}, nil
+}
+
+// options returns the options of lookups without a query.
+func (s *Service) options() Options {
+	return Options{Units: s.config().Units, Lang: s.lang}
+}
+
+// current returns the current conditions at the location, from the cache unless a refresh is requested.
+// It returns the provider that served the conditions and when they expire from the cache.
+func (s *Service) current(ctx context.Context, loc Location, opts Options, refresh bool) (*Observation, string, time.Time, error) {
+	key := fmt.Sprintf("%s|%s|%s", loc.Key(), opts.Units, opts.Lang)
+	if cached, expires, ok := s.data.GetWithExpiration(key); ok && !refresh {
+		c := cached.(*cachedObservation)
+		return c.obs, c.provider, expires, nil
+	}
+
+	obs, provider, err := s.providers.CurrentFrom(ctx, loc, opts)
+	if err != nil {
+		return nil, "", time.Time{}, err
+	}
+
+	if obs.Coord != (Coord{}) {
+		s.coordinates.Set(loc.Key(), obs.Coord, cache.NoExpiration)
+	}
+
+	s.cacheData(key, &cachedObservation{obs: obs, provider: provider}, s.config().CachePolicy(CurrentData).Expiry(obs.Time, time.Now()))
+	_, expires, _ := s.data.GetWithExpiration(key)
+
+	return obs, provider, expires, nil
+}
+
+// forecast returns the forecast at the coordinates, from the cache unless a refresh is requested.
+// It returns the provider that served the forecast and when it expires from the cache.
+func (s *Service) forecast(ctx context.Context, coord Coord, opts Options, refresh bool) (*Forecast, string, time.Time, error) {
+	key := forecastKey(coord, opts)
+	if cached, expires, ok := s.data.GetWithExpiration(key); ok && !refresh {
+		c := cached.(*cachedForecast)
+		return c.forecast, c.provider, expires, nil
+	}
+
+	f, provider, err := s.providers.ForecastFrom(ctx, coord, opts)
+	if err != nil {
+		return nil, "", time.Time{}, err
+	}
+
+	s.cacheData(key, &cachedForecast{forecast: f, provider: provider}, s.config().CachePolicy(ForecastData).Expiry(time.Time{}, time.Now()))
+	_, expires, _ := s.data.GetWithExpiration(key)
+
+	return f, provider, expires, nil
+}
+
+// cacheData caches observations and forecasts for the ttl. Stale data is still cached briefly,
+// a ttl of zero or less would be taken as the cache's default expiration.
+func (s *Service) cacheData(key string, v interface{}, ttl time.Duration) {
+	if ttl < minExpiry {
+		ttl = minExpiry
+	}
+
+	s.data.Set(key, v, ttl)
+}
+
+// coordinatesOf returns the remembered coordinates of the location, or looks up its current conditions to learn them.
+func (s *Service) coordinatesOf(ctx context.Context, loc Location, opts Options) (Coord, error) {
+	if coord, ok := s.coordinates.Get(loc.Key()); ok {
+		return coord.(Coord), nil
+	}
+
+	obs, _, _, err := s.current(ctx, loc, opts, false)
+	if err != nil {
+		return Coord{}, err
+	}
+
+	return obs.Coord, nil
+}
+
+func forecastKey(coord Coord, opts Options) string {
+	return fmt.Sprintf("%g,%g|%s|%s", coord.Lat, coord.Lon, opts.Units, opts.Lang)
+}
+
+// earliest returns the earlier of two expiration times, the zero time never expires.
+func earliest(a, b time.Time) time.Time {
+	if a.IsZero() || (!b.IsZero() && b.Before(a)) {
+		return b
+	}
+
+	return a
+}
+
+// hoursOf returns the hourly points within the local day that includes day, at a location offset from UTC by utcOffset seconds.
+func hoursOf(hourly []HourlyPoint, day time.Time, utcOffset int) []HourlyPoint {
+	zone := time.FixedZone("", utcOffset)
+	y, m, d := day.In(zone).Date()
+	start := time.Date(y, m, d, 0, 0, 0, 0, zone)
+	end := start.AddDate(0, 0, 1)
+
+	var points []HourlyPoint
+	for _, p := range hourly {
+		if !p.Time.Before(start) && p.Time.Before(end) {
+			points = append(points, p)
+		}
+	}
+
+	return points
+}
@@ -0,0 +1,159 @@
+package weather
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestService(t *testing.T) {
+	var mu sync.Mutex
+	calls := make(map[string]int)
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls[r.URL.Path]++
+		query = r.URL.Query().Get("appid") + " " + r.URL.Query().Get("units")
+		mu.Unlock()
+
+		switch r.URL.Path {
+		case "/data/2.5/weather":
+			fmt.Fprint(w, `{"coord": {"lon": -74.08, "lat": 4.61}, "name": "Bogotá", "sys": {"country": "CO"}, "main": {"temp": 20}}`)
+		case "/data/2.5/onecall":
+			fmt.Fprint(w, `{"daily": [{"dt": 1608825600, "temp": {"min": 9, "max": 19}}, {"dt": 1608912000, "temp": {"min": 10, "max": 18}}]}`)
+		default:
+			t.Errorf("Unexpected request %s", r.URL)
+		}
+	}))
+	defer server.Close()
+
+	var quota unlimitedQuota
+	cfg := Config{BaseURL: server.URL + "/data/2.5", APIKey: "key", Units: Metric, CacheExpirationDur: time.Minute}
+	service := NewService(&cfg, http.DefaultClient, WithQuota(&quota))
+	ctx := context.Background()
+	bogota := Location{City: "Bogota", Country: "co"}
+
+	obs, err := service.Current(ctx, bogota)
+	assert.Nil(t, err)
+	assert.Equal(t, "Bogotá", obs.Name)
+	assert.Equal(t, 20.0, obs.Temperature)
+
+	// The forecast uses the coordinates learned with the current conditions
+	f, err := service.Forecast(ctx, bogota, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, []DailyForecast{{Time: time.Unix(1608825600, 0).UTC(), TempMin: 9, TempMax: 19}}, f.Daily)
+
+	f, err = service.Forecast(ctx, bogota, 0)
+	assert.Nil(t, err)
+	assert.Len(t, f.Daily, 2)
+	assert.Equal(t, map[string]int{"/data/2.5/weather": 1, "/data/2.5/onecall": 1}, calls)
+
+	report, err := service.Lookup(ctx, Query{Location: bogota.Normalize(), Units: Metric, Lang: DefaultLang, Forecast: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, "Bogotá, CO", report.Response.LocationName)
+	assert.Equal(t, 18.0, report.Response.Forecast.TempMax)
+	assert.Equal(t, OpenWeatherProvider, report.Response.ForecastProvider)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), report.Expires, 5*time.Second)
+	assert.Equal(t, map[string]int{"/data/2.5/weather": 1, "/data/2.5/onecall": 1}, calls)
+
+	// Refreshing bypasses the cached data
+	_, err = service.Lookup(ctx, Query{Location: bogota.Normalize(), Units: Metric, Lang: DefaultLang, Forecast: 1, Refresh: true})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"/data/2.5/weather": 2, "/data/2.5/onecall": 2}, calls)
+	assert.Equal(t, 4, quota.calls)
+
+	_, err = service.Lookup(ctx, Query{Location: bogota.Normalize(), Units: Metric, Lang: DefaultLang, Forecast: 2})
+	assert.IsType(t, &ValidationError{}, err)
+
+	// Flushing keeps the coordinates
+	assert.Equal(t, CacheStats{Observations: 1, Forecasts: 1, Coordinates: 1}, service.CacheStats())
+	service.FlushCache()
+	assert.Equal(t, CacheStats{Coordinates: 1}, service.CacheStats())
+
+	// Reloading swaps the API keys, units and cache TTLs of the next lookups
+	service.Reload(&Config{BaseURL: cfg.BaseURL, APIKey: "next", Units: Imperial, CacheExpirationDur: time.Hour})
+	report, err = service.Lookup(ctx, Query{Location: bogota.Normalize(), Units: Metric, Lang: DefaultLang})
+	assert.Nil(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), report.Expires, 5*time.Second)
+	assert.Equal(t, "next metric", query)
+
+	_, err = service.Current(ctx, bogota)
+	assert.Nil(t, err)
+	assert.Equal(t, "next imperial", query)
+}
+
+func TestServiceStaleObservation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"dt": %d, "coord": {"lon": -74.08, "lat": 4.61}, "name": "Bogotá", "sys": {"country": "CO"}}`, time.Now().Add(-time.Hour).Unix())
+	}))
+	defer server.Close()
+
+	// The observation is older than its TTL and no minimum TTL is set, it is not held for the default expiration
+	cfg := Config{BaseURL: server.URL, APIKey: "key", Units: Metric, CacheExpirationDur: time.Hour,
+		CacheTTLs: map[DataType]TTLPolicy{CurrentData: {TTL: 10 * time.Minute, FromObservation: true}}}
+	service := NewService(&cfg, http.DefaultClient, WithQuota(&unlimitedQuota{}))
+
+	_, _, expires, err := service.current(context.Background(), Location{City: "bogota", Country: "CO"}, service.options(), false)
+	assert.Nil(t, err)
+	assert.WithinDuration(t, time.Now().Add(minExpiry), expires, time.Second)
+}
+
+func TestServiceCached(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if strings.Contains(r.URL.Path, "/onecall") {
+			fmt.Fprint(w, `{"daily": [{"dt": 1608825600, "pop": 0.4}, {"dt": 1608912000, "pop": 0.1}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"coord": {"lon": -74.08, "lat": 4.61}, "name": "Bogotá", "sys": {"country": "CO"}, "main": {"temp": 14.5}}`)
+	}))
+	defer server.Close()
+
+	cfg := Config{BaseURL: server.URL, APIKey: "key", Units: Metric, CacheExpirationDur: time.Hour}
+	service := NewService(&cfg, http.DefaultClient, WithQuota(&unlimitedQuota{}))
+	loc := Location{City: "Bogota", Country: "co"}
+
+	// Nothing is cached yet, the upstream is not called
+	_, ok := service.CachedCurrent(loc)
+	assert.False(t, ok)
+	_, ok = service.CachedForecast(loc, 1)
+	assert.False(t, ok)
+	assert.Equal(t, 0, calls)
+
+	_, err := service.Lookup(context.Background(), Query{Location: loc.Normalize(), Units: Metric, Lang: DefaultLang, Forecast: 0})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, calls)
+
+	obs, ok := service.CachedCurrent(loc)
+	assert.True(t, ok)
+	assert.Equal(t, 14.5, obs.Temperature)
+
+	f, ok := service.CachedForecast(loc, 1)
+	assert.True(t, ok)
+	assert.Len(t, f.Daily, 1)
+	assert.Equal(t, 0.4, f.Daily[0].PrecipitationProbability)
+	assert.Equal(t, 2, calls)
+}
+
+func TestHoursOf(t *testing.T) {
+	bogota := time.FixedZone("", -5*3600)
+	hourly := []HourlyPoint{
+		{Time: time.Date(2020, 12, 23, 23, 0, 0, 0, bogota).UTC()},
+		{Time: time.Date(2020, 12, 24, 0, 0, 0, 0, bogota).UTC()},
+		{Time: time.Date(2020, 12, 24, 23, 0, 0, 0, bogota).UTC()},
+		{Time: time.Date(2020, 12, 25, 0, 0, 0, 0, bogota).UTC()},
+	}
+
+	// Days are those of the location, not of UTC
+	points := hoursOf(hourly, time.Date(2020, 12, 24, 17, 0, 0, 0, time.UTC), -5*3600)
+	assert.Equal(t, hourly[1:3], points)
+	assert.Nil(t, hoursOf(hourly, time.Date(2020, 12, 27, 17, 0, 0, 0, time.UTC), -5*3600))
+}
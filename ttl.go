@@ -0,0 +1,52 @@
+package weather
+
+import "time"
+
+// DataType identifies a kind of weather data that is cached with its own policy.
+type DataType string
+
+// List of data types.
+const (
+	CurrentData  DataType = "current"
+	ForecastData DataType = "forecast"
+)
+
+// minExpiry is the shortest time data is cached for. Data that is already stale is still cached briefly,
+// as the cache takes an expiry of zero or less as its default expiration.
+const minExpiry = time.Second
+
+// TTLPolicy describes how long a type of data may be cached.
+type TTLPolicy struct {
+	// TTL is how long the data stays fresh.
+	TTL time.Duration
+
+	// FromObservation measures the TTL from the upstream observation time rather than from the time the data was fetched,
+	// so an observation that is already old is not held for another full window.
+	FromObservation bool
+
+	// MinTTL is the shortest time data is cached for, even when its observation is older than the TTL.
+	// Data is cached for at least a second when it is zero.
+	MinTTL time.Duration
+}
+
+// Expiry returns how long data observed at the given time may be cached, measured from now.
+// A zero observed time is treated as an observation made now.
+func (p TTLPolicy) Expiry(observed, now time.Time) time.Duration {
+	if !p.FromObservation || observed.IsZero() {
+		return p.TTL
+	}
+
+	ttl := observed.Add(p.TTL).Sub(now)
+	if ttl > p.TTL {
+		ttl = p.TTL
+	}
+
+	if ttl < p.MinTTL {
+		ttl = p.MinTTL
+	}
+	if ttl < minExpiry {
+		ttl = minExpiry
+	}
+
+	return ttl
+}
@@ -0,0 +1,31 @@
+package weather
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type ExpiryCase struct {
+	name     string
+	policy   TTLPolicy
+	observed time.Time
+	expected time.Duration
+}
+
+func TestExpiry(t *testing.T) {
+	now := time.Date(2020, 12, 17, 17, 0, 0, 0, time.UTC)
+	cases := []ExpiryCase{
+		{"Fixed TTL", TTLPolicy{TTL: 10 * time.Minute}, now.Add(-8 * time.Minute), 10 * time.Minute},
+		{"From observation", TTLPolicy{TTL: 10 * time.Minute, FromObservation: true}, now.Add(-8 * time.Minute), 2 * time.Minute},
+		{"Old observation", TTLPolicy{TTL: 10 * time.Minute, FromObservation: true, MinTTL: time.Minute}, now.Add(-time.Hour), time.Minute},
+		{"Stale observation", TTLPolicy{TTL: 10 * time.Minute, FromObservation: true}, now.Add(-time.Hour), time.Second},
+		{"Future observation", TTLPolicy{TTL: 10 * time.Minute, FromObservation: true}, now.Add(5 * time.Minute), 10 * time.Minute},
+		{"Unknown observation", TTLPolicy{TTL: 10 * time.Minute, FromObservation: true}, time.Time{}, 10 * time.Minute},
+	}
+
+	for i := range cases {
+		assert.Equal(t, cases[i].expected, cases[i].policy.Expiry(cases[i].observed, now), cases[i].name)
+	}
+}
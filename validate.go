@@ -0,0 +1,185 @@
+package weather
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrInvalidPayload is matched by every ValidationError.
+var ErrInvalidPayload = errors.New("Invalid upstream payload")
+
+// Limits of the values accepted from providers, temperatures are in celsius and wind speeds in meters per second.
+const (
+	minTemperature = -100.0
+	maxTemperature = 70.0
+	minPressure    = 300.0
+	maxPressure    = 1100.0
+	maxWindSpeed   = 120.0
+	maxDailyDays   = 16
+	maxHourlyHours = 16 * 24
+)
+
+// ValidationError reports a value of an upstream payload that is missing or outside its physical range.
+type ValidationError struct {
+	Field  string
+	Value  interface{}
+	Reason string
+}
+
+// Error returns the field along with the reason it is invalid.
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("Invalid upstream payload, %s %s (%v)", e.Field, e.Reason, e.Value)
+}
+
+// Unwrap returns ErrInvalidPayload.
+func (e *ValidationError) Unwrap() error {
+	return ErrInvalidPayload
+}
+
+// UpstreamError is returned when a provider responds with an error, e.g. open weather's {"cod": "404", "message": "city not found"}.
+type UpstreamError struct {
+	Provider string
+	Code     int
+	Message  string
+}
+
+// Error returns the code and message of the upstream error.
+func (e *UpstreamError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%s responded with status %d", e.Provider, e.Code)
+	}
+
+	return fmt.Sprintf("%s responded with status %d: %s", e.Provider, e.Code, e.Message)
+}
+
+// Unwrap returns ErrLocationNotFound for 404 errors, so that unknown locations are reported alike by every provider.
+func (e *UpstreamError) Unwrap() error {
+	if e.Code == http.StatusNotFound {
+		return ErrLocationNotFound
+	}
+
+	return nil
+}
+
+// Validate checks that the observation names its location and that its values are within their physical ranges.
+func (o *Observation) Validate() error {
+	if o.Name == "" {
+		return &ValidationError{Field: "name", Value: o.Name, Reason: "is missing"}
+	}
+
+	if err := o.Coord.Validate(); err != nil {
+		return err
+	}
+
+	return validateConditions("", o.Units, o.Temperature, o.Pressure, o.Humidity, o.CloudCover, o.WindSpeed, o.WindDeg)
+}
+
+// Validate checks that the forecast has a plausible number of days and hours and that every value is within its physical range.
+func (f *Forecast) Validate() error {
+	if len(f.Daily) == 0 || maxDailyDays < len(f.Daily) {
+		return &ValidationError{Field: "daily", Value: len(f.Daily), Reason: fmt.Sprintf("must have 1 to %d days", maxDailyDays)}
+	}
+
+	if maxHourlyHours < len(f.Hourly) {
+		return &ValidationError{Field: "hourly", Value: len(f.Hourly), Reason: fmt.Sprintf("must have at most %d hours", maxHourlyHours)}
+	}
+
+	for i, d := range f.Daily {
+		field := fmt.Sprintf("daily[%d].", i)
+		if err := validateConditions(field, f.Units, d.TempMax, d.Pressure, d.Humidity, d.CloudCover, d.WindSpeed, d.WindDeg); err != nil {
+			return err
+		}
+
+		if err := validateTemperature(field+"temp_min", f.Units, d.TempMin); err != nil {
+			return err
+		}
+
+		if d.TempMin > d.TempMax {
+			return &ValidationError{Field: field + "temp_min", Value: d.TempMin, Reason: "is above temp_max"}
+		}
+
+		if err := validateRange(field+"precipitation_probability", d.PrecipitationProbability, 0, 1); err != nil {
+			return err
+		}
+
+		if d.Precipitation < 0 {
+			return &ValidationError{Field: field + "precipitation", Value: d.Precipitation, Reason: "is negative"}
+		}
+	}
+
+	for i, h := range f.Hourly {
+		field := fmt.Sprintf("hourly[%d].", i)
+		if err := validateConditions(field, f.Units, h.Temperature, h.Pressure, h.Humidity, h.CloudCover, h.WindSpeed, h.WindDeg); err != nil {
+			return err
+		}
+
+		if err := validateRange(field+"precipitation_probability", h.PrecipitationProbability, 0, 1); err != nil {
+			return err
+		}
+	}
+
+	for i, a := range f.Alerts {
+		if a.End.Before(a.Start) {
+			return &ValidationError{Field: fmt.Sprintf("alerts[%d].end", i), Value: a.End, Reason: "is before start"}
+		}
+	}
+
+	return nil
+}
+
+// Validate checks that the coordinates are on earth.
+func (c Coord) Validate() error {
+	if err := validateRange("coord.lat", c.Lat, -90, 90); err != nil {
+		return err
+	}
+
+	return validateRange("coord.lon", c.Lon, -180, 180)
+}
+
+// validateConditions checks the values shared by observations and forecasts, a pressure of zero means it is unknown.
+func validateConditions(field string, units Unit, temperature, pressure, humidity, cloudCover, speed, deg float64) error {
+	if err := validateTemperature(field+"temperature", units, temperature); err != nil {
+		return err
+	}
+
+	if pressure != 0 {
+		if err := validateRange(field+"pressure", pressure, minPressure, maxPressure); err != nil {
+			return err
+		}
+	}
+
+	if err := validateRange(field+"humidity", humidity, 0, 100); err != nil {
+		return err
+	}
+
+	if err := validateRange(field+"cloud_cover", cloudCover, 0, 100); err != nil {
+		return err
+	}
+
+	if err := validateRange(field+"wind_speed", speed, 0, windSpeed(maxWindSpeed, units)); err != nil {
+		return err
+	}
+
+	return validateRange(field+"wind_deg", deg, 0, 360)
+}
+
+// validateTemperature checks a temperature in the units against the range of temperatures observed on earth.
+func validateTemperature(field string, units Unit, t float64) error {
+	switch units {
+	case Standard:
+		return validateRange(field, t, minTemperature+kelvinOffset, maxTemperature+kelvinOffset)
+	case Imperial:
+		return validateRange(field, t, minTemperature*9/5+32, maxTemperature*9/5+32)
+	}
+
+	return validateRange(field, t, minTemperature, maxTemperature)
+}
+
+func validateRange(field string, v, min, max float64) error {
+	if v < min || max < v {
+		return &ValidationError{Field: field, Value: v, Reason: fmt.Sprintf("is outside %g to %g", min, max)}
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package weather
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type validateCase struct {
+	name          string
+	payload       interface{ Validate() error }
+	expectedField string
+}
+
+func TestValidate(t *testing.T) {
+	obs := func(fn func(*Observation)) *Observation {
+		o := Observation{Name: "Bogotá", Units: Metric, Temperature: 18, Pressure: 1024, Humidity: 48, WindSpeed: 3, WindDeg: 230}
+		fn(&o)
+		return &o
+	}
+	forecast := func(fn func(*Forecast)) *Forecast {
+		f := Forecast{Units: Metric, Daily: []DailyForecast{{TempMin: 8, TempMax: 19, PrecipitationProbability: 0.9}}}
+		fn(&f)
+		return &f
+	}
+
+	cases := []validateCase{
+		{"valid observation", obs(func(o *Observation) {}), ""},
+		{"missing name", obs(func(o *Observation) { o.Name = "" }), "name"},
+		{"latitude", obs(func(o *Observation) { o.Coord.Lat = 91 }), "coord.lat"},
+		{"metric temperature", obs(func(o *Observation) { o.Temperature = 293 }), "temperature"},
+		{"standard temperature", obs(func(o *Observation) { o.Units, o.Temperature = Standard, 293 }), ""},
+		{"unknown pressure", obs(func(o *Observation) { o.Pressure = 0 }), ""},
+		{"pressure", obs(func(o *Observation) { o.Pressure = 10 }), "pressure"},
+		{"humidity", obs(func(o *Observation) { o.Humidity = 101 }), "humidity"},
+		{"negative wind speed", obs(func(o *Observation) { o.WindSpeed = -1 }), "wind_speed"},
+		{"negative wind direction", obs(func(o *Observation) { o.WindDeg = -10 }), "wind_deg"},
+		{"valid forecast", forecast(func(f *Forecast) {}), ""},
+		{"no days", forecast(func(f *Forecast) { f.Daily = nil }), "daily"},
+		{"too many days", forecast(func(f *Forecast) { f.Daily = make([]DailyForecast, 17) }), "daily"},
+		{"too many hours", forecast(func(f *Forecast) { f.Hourly = make([]HourlyPoint, 400) }), "hourly"},
+		{"minimum above maximum", forecast(func(f *Forecast) { f.Daily[0].TempMin = 20 }), "daily[0].temp_min"},
+		{"probability", forecast(func(f *Forecast) { f.Daily[0].PrecipitationProbability = 97 }), "daily[0].precipitation_probability"},
+		{"hourly humidity", forecast(func(f *Forecast) { f.Hourly = []HourlyPoint{{Humidity: -1}} }), "hourly[0].humidity"},
+		{"alert", forecast(func(f *Forecast) { f.Alerts = []Alert{{Start: time.Unix(2, 0), End: time.Unix(1, 0)}} }), "alerts[0].end"},
+	}
+
+	for _, c := range cases {
+		err := c.payload.Validate()
+		if c.expectedField == "" {
+			assert.Nil(t, err, c.name)
+			continue
+		}
+
+		var validationErr *ValidationError
+		if assert.True(t, errors.As(err, &validationErr), c.name) {
+			assert.Equal(t, c.expectedField, validationErr.Field, c.name)
+			assert.True(t, errors.Is(err, ErrInvalidPayload), c.name)
+		}
+	}
+}
+
+func TestUpstreamError(t *testing.T) {
+	err := &UpstreamError{Provider: OpenWeatherProvider, Code: 404, Message: "city not found"}
+	assert.Equal(t, "openweather responded with status 404: city not found", err.Error())
+	assert.True(t, errors.Is(err, ErrLocationNotFound))
+	assert.False(t, errors.Is(&UpstreamError{Code: 400}, ErrLocationNotFound))
+}